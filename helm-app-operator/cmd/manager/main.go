@@ -22,13 +22,10 @@ import (
 	k8sutil "github.com/operator-framework/operator-sdk/pkg/util/k8sutil"
 	sdkVersion "github.com/operator-framework/operator-sdk/version"
 	"github.com/sirupsen/logrus"
-	"k8s.io/helm/pkg/storage"
-	"k8s.io/helm/pkg/storage/driver"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/signals"
 
-	"github.com/operator-framework/helm-app-operator-kit/helm-app-operator/pkg/helm/client"
 	"github.com/operator-framework/helm-app-operator-kit/helm-app-operator/pkg/helm/controller"
 	"github.com/operator-framework/helm-app-operator-kit/helm-app-operator/pkg/helm/release"
 )
@@ -61,32 +58,46 @@ func main() {
 		logrus.Fatal(err)
 	}
 
-	logrus.Print("Registering Components.")
+	// stopCh is closed on SIGTERM/SIGINT. It stops the manager and, via
+	// controller.WatchOptions.StopCh, cancels the context of any
+	// reconcile still in flight so it doesn't outlive the process.
+	stopCh := signals.SetupSignalHandler()
 
-	// Create Tiller's storage backend and kubernetes client
-	storageBackend := storage.Init(driver.NewMemory())
-	tillerKubeClient, err := client.NewFromManager(mgr)
-	if err != nil {
-		logrus.Fatal(err)
-	}
+	logrus.Print("Registering Components.")
 
-	managers, err := release.NewManagersFromEnv(storageBackend, tillerKubeClient)
+	// Each release is installed, upgraded, and uninstalled through the
+	// in-process Helm v3 action engine, with release history persisted as
+	// Secrets in the owning CR's own namespace.
+	managerFactories, err := release.NewManagerFactoriesFromEnv(mgr)
 	if err != nil {
 		logrus.Fatal(err)
 	}
 
-	for gvk, manager := range managers {
+	for gvk, managerFactory := range managerFactories {
+		resyncPeriod := 5 * time.Second
+		if pollInterval, ok := managerFactory.ResyncPeriod(); ok {
+			resyncPeriod = pollInterval
+		}
+		// Defaulted so a hung install/upgrade can't block shutdown
+		// indefinitely when reconcileTimeout is left unset in watches.yaml.
+		reconcileTimeout := 5 * time.Minute
+		if timeout, ok := managerFactory.ReconcileTimeout(); ok {
+			reconcileTimeout = timeout
+		}
 		// Register the controller with the manager.
 		controller.Add(mgr, controller.WatchOptions{
-			Namespace:    namespace,
-			GVK:          gvk,
-			Manager:      manager,
-			ResyncPeriod: 5 * time.Second,
+			Namespace:            namespace,
+			GVK:                  gvk,
+			ManagerFactory:       managerFactory,
+			ResyncPeriod:         resyncPeriod,
+			ReconcileTimeout:     reconcileTimeout,
+			HasStatusSubresource: managerFactory.HasStatusSubresource(),
+			StopCh:               stopCh,
 		})
 	}
 
 	logrus.Print("Starting the Cmd.")
 
 	// Start the Cmd
-	logrus.Fatal(mgr.Start(signals.SetupSignalHandler()))
+	logrus.Fatal(mgr.Start(stopCh))
 }
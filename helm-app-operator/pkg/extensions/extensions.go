@@ -0,0 +1,42 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package extensions lets an operator author hook into a HelmOperatorReconciler's
+// reconcile loop, for domain-specific validation, license checks, or resource
+// labeling around each Helm apply, without forking the reconciler itself.
+package extensions
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// UpdateStatusFunc mutates a resource's status in place, returning whether
+// it actually changed anything. ReconcileExtension hooks enqueue
+// UpdateStatusFuncs rather than mutating the resource's status directly,
+// since the reconciler is about to overwrite status.release and
+// status.conditions itself; batching the mutations lets the reconciler
+// apply them after its own write, so extension-set custom status fields
+// survive instead of being clobbered.
+type UpdateStatusFunc func(o *unstructured.Unstructured) bool
+
+// ReconcileExtension is a hook a HelmOperatorReconciler runs, once per
+// reconcile, either before or after its Helm action (install, upgrade,
+// uninstall, or drift reconciliation). updateStatus enqueues a status
+// mutation for the reconciler to apply once it's done writing its own
+// status fields. A non-nil return aborts the reconcile, surfacing as a
+// ReleaseFailed condition on the resource.
+type ReconcileExtension func(ctx context.Context, o *unstructured.Unstructured, updateStatus func(UpdateStatusFunc), log logr.Logger) error
@@ -15,58 +15,124 @@
 package controller
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
-	"github.com/sirupsen/logrus"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	crthandler "sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	"github.com/operator-framework/helm-app-operator-kit/helm-app-operator/pkg/extensions"
 	"github.com/operator-framework/helm-app-operator-kit/helm-app-operator/pkg/helm/release"
 )
 
 // WatchOptions contains the necessary values to create a new controller that
 // manages helm releases in a particular namespace based on a GVK watch.
 type WatchOptions struct {
-	Namespace      string
-	GVK            schema.GroupVersionKind
-	ManagerFactory release.ManagerFactory
-	ResyncPeriod   time.Duration
+	Namespace        string
+	GVK              schema.GroupVersionKind
+	ManagerFactory   release.ManagerFactory
+	ResyncPeriod     time.Duration
+	ReconcileTimeout time.Duration
+
+	// HasStatusSubresource should be true when the watched GVK's CRD
+	// declares the status subresource, so the reconciler writes status
+	// through the status subresource endpoint instead of a plain Update.
+	HasStatusSubresource bool
+
+	// StopCh is closed when the operator process begins shutting down
+	// (e.g. on SIGTERM). Reconciles in flight at that point have their
+	// context canceled so they don't keep a Helm action running past
+	// process shutdown. May be nil, in which case reconciles are never
+	// canceled this way.
+	StopCh <-chan struct{}
+}
+
+// Option configures optional behavior of the HelmOperatorReconciler built
+// by Add.
+type Option func(*HelmOperatorReconciler)
+
+// WithPreReconcileExtension registers ext to run before every reconcile's
+// Helm action. A non-nil error from ext aborts the reconcile before any
+// install, upgrade, uninstall, or drift reconciliation is attempted.
+func WithPreReconcileExtension(ext extensions.ReconcileExtension) Option {
+	return func(r *HelmOperatorReconciler) {
+		r.PreExtensions = append(r.PreExtensions, ext)
+	}
+}
+
+// WithPostReconcileExtension registers ext to run after every reconcile's
+// Helm action succeeds, for validation, license checks, or resource
+// labeling that depends on the release having been applied.
+func WithPostReconcileExtension(ext extensions.ReconcileExtension) Option {
+	return func(r *HelmOperatorReconciler) {
+		r.PostExtensions = append(r.PostExtensions, ext)
+	}
 }
 
 // Add creates a new helm operator controller and adds it to the manager
-func Add(mgr manager.Manager, options WatchOptions) {
+func Add(mgr manager.Manager, options WatchOptions, opts ...Option) {
 	if options.ResyncPeriod == 0 {
 		options.ResyncPeriod = time.Minute
 	}
+	controllerName := fmt.Sprintf("%v-controller", strings.ToLower(options.GVK.Kind))
+	log := logf.Log.WithName(controllerName)
+
 	r := &HelmOperatorReconciler{
-		Client:         mgr.GetClient(),
-		GVK:            options.GVK,
-		ManagerFactory: options.ManagerFactory,
-		ResyncPeriod:   options.ResyncPeriod,
+		Client:               mgr.GetClient(),
+		GVK:                  options.GVK,
+		ManagerFactory:       options.ManagerFactory,
+		ResyncPeriod:         options.ResyncPeriod,
+		ReconcileTimeout:     options.ReconcileTimeout,
+		HasStatusSubresource: options.HasStatusSubresource,
+		Context:              contextForStopChannel(options.StopCh),
+		Log:                  log,
+		EventRecorder:        mgr.GetEventRecorderFor(controllerName),
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
 
 	// Register the GVK with the schema
 	mgr.GetScheme().AddKnownTypeWithName(options.GVK, &unstructured.Unstructured{})
 	metav1.AddToGroupVersion(mgr.GetScheme(), options.GVK.GroupVersion())
 
-	controllerName := fmt.Sprintf("%v-controller", strings.ToLower(options.GVK.Kind))
 	c, err := controller.New(controllerName, mgr, controller.Options{Reconciler: r})
 	if err != nil {
-		logrus.Fatal(err)
+		log.Error(err, "failed to create controller")
+		os.Exit(1)
 	}
 
 	o := &unstructured.Unstructured{}
 	o.SetGroupVersionKind(options.GVK)
 	if err := c.Watch(&source.Kind{Type: o}, &crthandler.EnqueueRequestForObject{}); err != nil {
-		logrus.Fatal(err)
+		log.Error(err, "failed to watch resource")
+		os.Exit(1)
 	}
 
-	logrus.Infof("Watching %s, %s, %s, %d", options.GVK.GroupVersion(), options.GVK.Kind, options.Namespace, options.ResyncPeriod)
+	log.Info("Watching resource", "apiVersion", options.GVK.GroupVersion().String(), "kind", options.GVK.Kind, "namespace", options.Namespace, "resyncPeriod", options.ResyncPeriod)
+}
+
+// contextForStopChannel returns a context.Context that is canceled as soon
+// as stopCh is closed, so in-flight reconciles can propagate the same
+// SIGTERM that's stopping the manager. A nil stopCh returns
+// context.Background(), which is never canceled.
+func contextForStopChannel(stopCh <-chan struct{}) context.Context {
+	if stopCh == nil {
+		return context.Background()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+	return ctx
 }
@@ -16,16 +16,24 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
-	"github.com/sirupsen/logrus"
+	"github.com/go-logr/logr"
 
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	rpb "helm.sh/helm/v3/pkg/release"
+
+	"github.com/operator-framework/helm-app-operator-kit/helm-app-operator/pkg/extensions"
+	"github.com/operator-framework/helm-app-operator-kit/helm-app-operator/pkg/helm/internal/metrics"
 	"github.com/operator-framework/helm-app-operator-kit/helm-app-operator/pkg/helm/internal/types"
 	"github.com/operator-framework/helm-app-operator-kit/helm-app-operator/pkg/helm/internal/util"
 	"github.com/operator-framework/helm-app-operator-kit/helm-app-operator/pkg/helm/release"
@@ -39,6 +47,37 @@ type HelmOperatorReconciler struct {
 	GVK            schema.GroupVersionKind
 	ManagerFactory release.ManagerFactory
 	ResyncPeriod   time.Duration
+	Log            logr.Logger
+
+	// EventRecorder records Installed/Upgraded/Reconciled/Uninstalled events
+	// (and their *Error counterparts) against the reconciled resource, so
+	// `kubectl describe` on a watched CR shows its release history
+	// alongside the status conditions.
+	EventRecorder record.EventRecorder
+
+	// PreExtensions run, in order, before the Helm action for every
+	// reconcile. PostExtensions run, in order, after the Helm action
+	// succeeds. Both are normally populated via WithPreReconcileExtension
+	// and WithPostReconcileExtension rather than set directly.
+	PreExtensions  []extensions.ReconcileExtension
+	PostExtensions []extensions.ReconcileExtension
+
+	// HasStatusSubresource indicates the watched GVK's CRD declares the
+	// status subresource, so status writes must go through
+	// r.Client.Status().Update rather than a plain Update, which the API
+	// server would reject (or which would silently no-op the status
+	// change) once the subresource is enabled.
+	HasStatusSubresource bool
+
+	// ReconcileTimeout bounds how long a single call to Reconcile may run
+	// before its context is canceled. Zero means no per-reconcile bound.
+	ReconcileTimeout time.Duration
+
+	// Context is the base context each reconcile's context is derived
+	// from. It's expected to be canceled when the operator process begins
+	// shutting down, so long-running Helm actions don't outlive it. A nil
+	// Context is treated as context.Background().
+	Context context.Context
 }
 
 const (
@@ -50,57 +89,163 @@ const (
 // release changes are necessary, Reconcile will create or patch the underlying
 // resources to match the expected release manifest.
 func (r HelmOperatorReconciler) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	gvk := r.GVK.String()
+	start := time.Now()
+	result := "success"
+	defer func() {
+		metrics.ReconcileTotal.WithLabelValues(gvk, result).Inc()
+		metrics.ReconcileDurationSeconds.WithLabelValues(gvk).Observe(time.Since(start).Seconds())
+	}()
+
+	baseCtx := r.Context
+	if baseCtx == nil {
+		baseCtx = context.Background()
+	}
+	ctx := baseCtx
+	if r.ReconcileTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(baseCtx, r.ReconcileTimeout)
+		defer cancel()
+	}
+
 	o := &unstructured.Unstructured{}
 	o.SetGroupVersionKind(r.GVK)
 	o.SetNamespace(request.Namespace)
 	o.SetName(request.Name)
-	logrus.Debugf("Processing %s", util.ResourceString(o))
+	log := r.Log.WithValues("namespace", request.Namespace, "name", request.Name)
+	log.V(1).Info("Reconciling")
 
-	err := r.Client.Get(context.TODO(), request.NamespacedName, o)
+	err := r.Client.Get(ctx, request.NamespacedName, o)
 	if apierrors.IsNotFound(err) {
 		return reconcile.Result{}, nil
 	}
 	if err != nil {
-		logrus.Errorf("failed to lookup %s: %s", util.ResourceString(o), err)
+		log.Error(err, "failed to look up resource")
+		result = "error"
 		return reconcile.Result{}, err
 	}
 
 	deleted := o.GetDeletionTimestamp() != nil
 	pendingFinalizers := o.GetFinalizers()
 	if !deleted && !contains(pendingFinalizers, finalizer) {
-		logrus.Debugf("Adding finalizer \"%s\" to %s", finalizer, util.ResourceString(o))
+		log.V(1).Info("Adding finalizer", "finalizer", finalizer)
 		finalizers := append(pendingFinalizers, finalizer)
 		o.SetFinalizers(finalizers)
-		err := r.Client.Update(context.TODO(), o)
+		err := r.Client.Update(ctx, o)
+		if err != nil {
+			result = "error"
+		}
 		return reconcile.Result{}, err
 	}
 
-	manager := r.ManagerFactory.NewManager(o)
+	manager, err := r.ManagerFactory.NewManager(o)
+	if err != nil {
+		log.Error(err, "failed to build release manager")
+		result = "error"
+		return reconcile.Result{}, err
+	}
 	status := types.StatusFor(o)
 	releaseName := manager.ReleaseName()
+	log = log.WithValues("release", releaseName)
+
+	var statusUpdates []extensions.UpdateStatusFunc
+	enqueueStatusUpdate := func(f extensions.UpdateStatusFunc) {
+		statusUpdates = append(statusUpdates, f)
+	}
 
-	if err := manager.Sync(context.TODO()); err != nil {
-		logrus.Errorf("failed to sync release for %s release=%s: %s", util.ResourceString(o), releaseName, err)
+	if err := r.runExtensions(ctx, r.PreExtensions, o, enqueueStatusUpdate, log); err != nil {
+		log.Error(err, "pre-reconcile extension failed")
+		setReleaseFailed(status, err)
+		_ = r.updateResource(ctx, o, status, statusUpdates)
+		result = "error"
+		return reconcile.Result{}, err
+	}
+
+	if err := manager.Sync(ctx); err != nil {
+		log.Error(err, "failed to sync release")
+		reason := types.ReasonApplyFailed
+		if errors.Is(err, release.ErrValidationFailed) {
+			reason = types.ReasonValidationFailed
+		}
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionInitialized,
+			Status:  types.ConditionStatusFalse,
+			Reason:  reason,
+			Message: err.Error(),
+		})
+		status.SetPhase(types.PhaseFailed, reason, err.Error())
+		_ = r.updateResource(ctx, o, status, statusUpdates)
+		result = "error"
 		return reconcile.Result{}, err
 	}
+	status.SetCondition(types.HelmAppCondition{
+		Type:   types.ConditionInitialized,
+		Status: types.ConditionStatusTrue,
+		Reason: types.ReasonApplySuccessful,
+	})
 
 	if deleted {
 		if !contains(pendingFinalizers, finalizer) {
-			logrus.Infof("Resource %s is terminated, skipping reconciliation", util.ResourceString(o))
+			log.V(1).Info("Resource is terminated, skipping reconciliation")
 			return reconcile.Result{}, nil
 		}
 
-		uninstalledRelease, err := manager.UninstallRelease(context.TODO())
-		if err != nil && err != release.ErrNotFound {
-			logrus.Errorf("failed to uninstall release for %s release=%s: %s", util.ResourceString(o), releaseName, err)
+		uninstalledRelease, err := manager.UninstallRelease(ctx)
+		if err != nil && !errors.Is(err, release.ErrNotFound) {
+			log.Error(err, "failed to uninstall release")
+			r.event(o, corev1.EventTypeWarning, "UninstallError", fmt.Sprintf("Failed to uninstall release %s: %s", releaseName, err))
+			status.SetCondition(types.HelmAppCondition{
+				Type:    types.ConditionReleaseFailed,
+				Status:  types.ConditionStatusTrue,
+				Reason:  types.ReasonApplyFailed,
+				Message: err.Error(),
+			})
+			_ = r.updateResource(ctx, o, status, statusUpdates)
+			result = "error"
 			return reconcile.Result{}, err
 		}
-		if err == release.ErrNotFound {
-			logrus.Infof("Release %s for resource %s not found, removing finalizer", releaseName, util.ResourceString(o))
+		metrics.ReleaseTotal.WithLabelValues(gvk, "uninstall").Inc()
+		// uninstalledManifest is the manifest of the release that was just
+		// removed, or, on a later reconcile of the same deletion (the
+		// release is already gone from storage by then), the manifest
+		// stashed on status by that first pass.
+		uninstalledManifest := ""
+		if errors.Is(err, release.ErrNotFound) {
+			log.Info("Release not found, removing finalizer")
+			if status.Release != nil {
+				uninstalledManifest = status.Release.Manifest
+			}
 		} else {
-			diff := util.Diff(uninstalledRelease.GetManifest(), "")
-			logrus.Infof("Uninstalled release for %s release=%s; diff:\n%s", util.ResourceString(o), releaseName, diff)
+			diff := util.Diff(uninstalledRelease.Manifest, "")
+			log.Info("Uninstalled release", "diff", diff)
+			uninstalledManifest = uninstalledRelease.Manifest
+			status.SetRelease(uninstalledRelease)
+			r.event(o, corev1.EventTypeNormal, "Uninstalled", fmt.Sprintf("Uninstalled release %s", releaseName))
+		}
+
+		if isTruthy(o.GetAnnotations()[release.UninstallWaitAnnotation]) && uninstalledManifest != "" {
+			remain, err := manager.UninstallResourcesRemain(ctx, uninstalledManifest)
+			if err != nil {
+				log.Error(err, "failed to check for remaining release resources")
+				_ = r.updateResource(ctx, o, status, statusUpdates)
+				result = "error"
+				return reconcile.Result{}, err
+			}
+			if remain {
+				log.V(1).Info("Waiting for release resources to be removed before releasing finalizer")
+				_ = r.updateResource(ctx, o, status, statusUpdates)
+				return reconcile.Result{RequeueAfter: r.ResyncPeriod}, nil
+			}
 		}
+
+		metrics.SetReleaseInfo(gvk, o.GetName(), o.GetNamespace(), "", "uninstalled")
+		status.SetCondition(types.HelmAppCondition{
+			Type:   types.ConditionDeployed,
+			Status: types.ConditionStatusFalse,
+			Reason: types.ReasonApplySuccessful,
+		})
+		status.SetRelease(nil)
+		status.SetPhase(types.PhaseNone, types.ReasonApplySuccessful, "")
 		finalizers := []string{}
 		for _, pendingFinalizer := range pendingFinalizers {
 			if pendingFinalizer != finalizer {
@@ -108,53 +253,298 @@ func (r HelmOperatorReconciler) Reconcile(request reconcile.Request) (reconcile.
 			}
 		}
 		o.SetFinalizers(finalizers)
-		err = r.Client.Update(context.TODO(), o)
+		if err := r.runPostExtensions(ctx, o, status, enqueueStatusUpdate, log); err != nil {
+			_ = r.updateResource(ctx, o, status, statusUpdates)
+			result = "error"
+			return reconcile.Result{}, err
+		}
+		err = r.updateResource(ctx, o, status, statusUpdates)
+		if err != nil {
+			result = "error"
+		}
 		return reconcile.Result{}, err
 	}
 
 	if !manager.IsInstalled() {
-		installedRelease, err := manager.InstallRelease(context.TODO())
+		installedRelease, err := manager.InstallRelease(ctx)
 		if err != nil {
-			logrus.Errorf("failed to install release for %s release=%s: %s", util.ResourceString(o), releaseName, err)
+			log.Error(err, "failed to install release")
+			r.event(o, corev1.EventTypeWarning, "InstallError", fmt.Sprintf("Failed to install release %s: %s", releaseName, err))
+			setReleaseFailed(status, err)
+			_ = r.updateResource(ctx, o, status, statusUpdates)
+			result = "error"
 			return reconcile.Result{}, err
 		}
-		diff := util.Diff("", installedRelease.GetManifest())
-		logrus.Infof("Installed release for %s release=%s; diff:\n%s", util.ResourceString(o), releaseName, diff)
+		metrics.ReleaseTotal.WithLabelValues(gvk, "install").Inc()
+		diff := util.Diff("", installedRelease.Manifest)
+		log.Info("Installed release", "diff", diff)
+		metrics.SetReleaseInfo(gvk, o.GetName(), o.GetNamespace(), installedRelease.Chart.Metadata.Version, string(installedRelease.Info.Status))
+		r.event(o, corev1.EventTypeNormal, "Installed", fmt.Sprintf("Installed release %s version %s", releaseName, installedRelease.Chart.Metadata.Version))
 
 		status.SetRelease(installedRelease)
-		status.SetPhase(types.PhaseApplied, types.ReasonApplySuccessful, installedRelease.GetInfo().GetStatus().GetNotes())
-		err = r.updateResource(o, status)
+		status.SetPhase(types.PhaseApplied, types.ReasonApplySuccessful, installedRelease.Info.Notes)
+		setDeployed(status, installedRelease)
+		recordHooks(status, installedRelease.Hooks)
+		r.recordResourceReadiness(ctx, manager, status, installedRelease.Manifest, log)
+		if err := r.runPostExtensions(ctx, o, status, enqueueStatusUpdate, log); err != nil {
+			_ = r.updateResource(ctx, o, status, statusUpdates)
+			result = "error"
+			return reconcile.Result{}, err
+		}
+		err = r.updateResource(ctx, o, status, statusUpdates)
+		if err != nil {
+			result = "error"
+		}
 		return reconcile.Result{RequeueAfter: r.ResyncPeriod}, err
 	}
 
 	if manager.IsUpdateRequired() {
-		previousRelease, updatedRelease, err := manager.UpdateRelease(context.TODO())
+		previousRelease, updatedRelease, err := manager.UpdateRelease(ctx)
 		if err != nil {
-			logrus.Errorf("failed to update release for %s release=%s: %s", util.ResourceString(o), releaseName, err)
+			if errors.Is(err, release.ErrRolledBack) {
+				log.Error(err, "failed to update release, rolled back")
+				metrics.ReleaseTotal.WithLabelValues(gvk, "rollback").Inc()
+				r.event(o, corev1.EventTypeWarning, "UpgradeError", fmt.Sprintf("Failed to upgrade release %s, rolled back: %s", releaseName, err))
+				status.SetCondition(types.HelmAppCondition{
+					Type:    types.ConditionRolledBack,
+					Status:  types.ConditionStatusTrue,
+					Reason:  types.ReasonRollbackSuccessful,
+					Message: err.Error(),
+				})
+				setReleaseFailed(status, err)
+				_ = r.updateResource(ctx, o, status, statusUpdates)
+				result = "error"
+				return reconcile.Result{}, err
+			}
+			log.Error(err, "failed to update release")
+			r.event(o, corev1.EventTypeWarning, "UpgradeError", fmt.Sprintf("Failed to upgrade release %s: %s", releaseName, err))
+			setReleaseFailed(status, err)
+			_ = r.updateResource(ctx, o, status, statusUpdates)
+			result = "error"
 			return reconcile.Result{}, err
 		}
-		diff := util.Diff(previousRelease.GetManifest(), updatedRelease.GetManifest())
-		logrus.Infof("Updated release for %s release=%s; diff:\n%s", util.ResourceString(o), releaseName, diff)
+		metrics.ReleaseTotal.WithLabelValues(gvk, "upgrade").Inc()
+		diff := util.Diff(previousRelease.Manifest, updatedRelease.Manifest)
+		log.Info("Updated release", "diff", diff)
+		metrics.SetReleaseInfo(gvk, o.GetName(), o.GetNamespace(), updatedRelease.Chart.Metadata.Version, string(updatedRelease.Info.Status))
+		r.event(o, corev1.EventTypeNormal, "Upgraded", fmt.Sprintf("Upgraded release %s to version %s", releaseName, updatedRelease.Chart.Metadata.Version))
 
 		status.SetRelease(updatedRelease)
-		status.SetPhase(types.PhaseApplied, types.ReasonApplySuccessful, updatedRelease.GetInfo().GetStatus().GetNotes())
-		err = r.updateResource(o, status)
+		status.SetPhase(types.PhaseApplied, types.ReasonApplySuccessful, updatedRelease.Info.Notes)
+		setDeployed(status, updatedRelease)
+		recordHooks(status, updatedRelease.Hooks)
+		r.recordResourceReadiness(ctx, manager, status, updatedRelease.Manifest, log)
+		if err := r.runPostExtensions(ctx, o, status, enqueueStatusUpdate, log); err != nil {
+			_ = r.updateResource(ctx, o, status, statusUpdates)
+			result = "error"
+			return reconcile.Result{}, err
+		}
+		err = r.updateResource(ctx, o, status, statusUpdates)
+		if err != nil {
+			result = "error"
+		}
 		return reconcile.Result{RequeueAfter: r.ResyncPeriod}, err
 	}
 
-	_, err = manager.ReconcileRelease(context.TODO())
+	reconciledRelease, err := manager.ReconcileRelease(ctx)
 	if err != nil {
-		logrus.Errorf("failed to reconcile release for %s release=%s: %s", util.ResourceString(o), releaseName, err)
+		log.Error(err, "failed to reconcile release")
+		r.event(o, corev1.EventTypeWarning, "ReconcileError", fmt.Sprintf("Failed to reconcile release %s: %s", releaseName, err))
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionIrreconcilable,
+			Status:  types.ConditionStatusTrue,
+			Reason:  types.ReasonApplyFailed,
+			Message: err.Error(),
+		})
+		status.SyncPhaseFromConditions()
+		_ = r.updateResource(ctx, o, status, statusUpdates)
+		result = "error"
+		return reconcile.Result{}, err
+	}
+	status.SetCondition(types.HelmAppCondition{
+		Type:   types.ConditionIrreconcilable,
+		Status: types.ConditionStatusFalse,
+		Reason: types.ReasonApplySuccessful,
+	})
+	status.SyncPhaseFromConditions()
+	log.V(1).Info("Reconciled release")
+	r.event(o, corev1.EventTypeNormal, "Reconciled", fmt.Sprintf("Reconciled release %s", releaseName))
+	if reconciledRelease != nil {
+		r.recordResourceReadiness(ctx, manager, status, reconciledRelease.Manifest, log)
+	}
+
+	if err := r.runPostExtensions(ctx, o, status, enqueueStatusUpdate, log); err != nil {
+		_ = r.updateResource(ctx, o, status, statusUpdates)
+		result = "error"
+		return reconcile.Result{}, err
+	}
+	if err := r.updateResource(ctx, o, status, statusUpdates); err != nil {
+		result = "error"
 		return reconcile.Result{}, err
 	}
-	logrus.Infof("Reconciled release for %s release=%s", util.ResourceString(o), releaseName)
 
 	return reconcile.Result{RequeueAfter: r.ResyncPeriod}, nil
 }
 
-func (r HelmOperatorReconciler) updateResource(o *unstructured.Unstructured, status *types.HelmAppStatus) error {
-	o.Object["status"] = status
-	return r.Client.Update(context.TODO(), o)
+// event records an event against o if r.EventRecorder is set, so operators
+// that build a HelmOperatorReconciler directly (rather than through
+// controller.Add) aren't required to provide one.
+func (r HelmOperatorReconciler) event(o *unstructured.Unstructured, eventType, reason, message string) {
+	if r.EventRecorder == nil {
+		return
+	}
+	r.EventRecorder.Event(o, eventType, reason, message)
+}
+
+// runExtensions runs exts, in order, stopping at (and returning) the first
+// error.
+func (r HelmOperatorReconciler) runExtensions(ctx context.Context, exts []extensions.ReconcileExtension, o *unstructured.Unstructured, enqueue func(extensions.UpdateStatusFunc), log logr.Logger) error {
+	for _, ext := range exts {
+		if err := ext(ctx, o, enqueue, log); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runPostExtensions runs r.PostExtensions after a successful Helm action,
+// marking status ReleaseFailed if any of them errors.
+func (r HelmOperatorReconciler) runPostExtensions(ctx context.Context, o *unstructured.Unstructured, status *types.HelmAppStatus, enqueue func(extensions.UpdateStatusFunc), log logr.Logger) error {
+	if err := r.runExtensions(ctx, r.PostExtensions, o, enqueue, log); err != nil {
+		log.Error(err, "post-reconcile extension failed")
+		setReleaseFailed(status, err)
+		return err
+	}
+	return nil
+}
+
+// setDeployed marks the release as Deployed, unless Helm itself reports the
+// release's status as FAILED (which can happen without InstallRelease or
+// UpdateRelease returning an error, e.g. when a post-install hook fails but
+// the release is still recorded).
+func setDeployed(status *types.HelmAppStatus, rel *rpb.Release) {
+	if rel.Info != nil && rel.Info.Status == rpb.StatusFailed {
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionReleaseFailed,
+			Status:  types.ConditionStatusTrue,
+			Reason:  types.ReasonApplyFailed,
+			Message: rel.Info.Description,
+		})
+		return
+	}
+	status.SetCondition(types.HelmAppCondition{
+		Type:    types.ConditionDeployed,
+		Status:  types.ConditionStatusTrue,
+		Reason:  types.ReasonApplySuccessful,
+		Release: rel,
+	})
+	status.SetCondition(types.HelmAppCondition{
+		Type:   types.ConditionReleaseFailed,
+		Status: types.ConditionStatusFalse,
+		Reason: types.ReasonApplySuccessful,
+	})
+}
+
+// hookMessageMaxLen bounds how much of a failed hook's manifest ends up in
+// status.message, so a large hook template doesn't blow out the resource's
+// status subresource.
+const hookMessageMaxLen = 2048
+
+// recordHooks records hooks on status and, if any of them failed, overrides
+// the ReleaseFailed condition with ReasonHookFailed and a truncated
+// snippet of that hook's manifest, so "the release applied but a hook
+// failed" is distinguishable from a manifest apply failure without
+// kubectl exec into the operator. If every hook succeeded, the condition
+// is marked false with ReasonHookSucceeded instead of the more generic
+// ReasonApplySuccessful setDeployed already set.
+func recordHooks(status *types.HelmAppStatus, hooks []*rpb.Hook) {
+	status.SetHooks(hooks)
+	if failed := firstFailedHook(hooks); failed != nil {
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionReleaseFailed,
+			Status:  types.ConditionStatusTrue,
+			Reason:  types.ReasonHookFailed,
+			Message: truncate(fmt.Sprintf("hook %s (%s) failed:\n%s", failed.Name, failed.Kind, failed.Manifest), hookMessageMaxLen),
+		})
+		return
+	}
+	if len(hooks) > 0 {
+		status.SetCondition(types.HelmAppCondition{
+			Type:   types.ConditionReleaseFailed,
+			Status: types.ConditionStatusFalse,
+			Reason: types.ReasonHookSucceeded,
+		})
+	}
+}
+
+// firstFailedHook returns the first hook whose last run failed, or nil.
+func firstFailedHook(hooks []*rpb.Hook) *rpb.Hook {
+	for _, h := range hooks {
+		if h.LastRun.Phase == rpb.HookPhaseFailed {
+			return h
+		}
+	}
+	return nil
+}
+
+// truncate cuts s to its first max bytes and appends a "... (truncated)"
+// marker, so a large hook manifest doesn't end up verbatim in
+// status.message. A string of at most max bytes is returned unchanged.
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "... (truncated)"
+}
+
+// recordResourceReadiness inventories manifest's resources and rolls their
+// readiness up into status.Resources/status.Ready. It's best-effort: a
+// failure polling live resource state logs a warning and leaves status's
+// existing inventory alone rather than failing an otherwise-successful
+// reconcile.
+func (r HelmOperatorReconciler) recordResourceReadiness(ctx context.Context, manager release.Manager, status *types.HelmAppStatus, manifest string, log logr.Logger) {
+	resources, ready, err := manager.ResourceReadiness(ctx, manifest)
+	if err != nil {
+		log.Error(err, "failed to determine resource readiness")
+		return
+	}
+	status.SetResources(resources, ready)
+}
+
+func setReleaseFailed(status *types.HelmAppStatus, err error) {
+	status.SetPhase(types.PhaseFailed, types.ReasonApplyFailed, err.Error())
+	status.SetCondition(types.HelmAppCondition{
+		Type:    types.ConditionReleaseFailed,
+		Status:  types.ConditionStatusTrue,
+		Reason:  types.ReasonApplyFailed,
+		Message: err.Error(),
+	})
+}
+
+// updateResource field-wise merges status into o's existing status
+// subresource map, preserving any key this package doesn't own (set by an
+// external controller or a ReconcileExtension), then applies statusUpdates
+// on top of that, before persisting o. If r.HasStatusSubresource is set,
+// the write goes through the status subresource endpoint instead of a
+// plain Update, so it doesn't also attempt to write o's spec.
+func (r HelmOperatorReconciler) updateResource(ctx context.Context, o *unstructured.Unstructured, status *types.HelmAppStatus, statusUpdates []extensions.UpdateStatusFunc) error {
+	existing, _ := o.Object["status"].(map[string]interface{})
+	merged, err := status.MergeInto(existing)
+	if err != nil {
+		return err
+	}
+	o.Object["status"] = merged
+	for _, update := range statusUpdates {
+		update(o)
+	}
+	if r.HasStatusSubresource {
+		return r.Client.Status().Update(ctx, o)
+	}
+	return r.Client.Update(ctx, o)
+}
+
+func isTruthy(v string) bool {
+	return v == "true" || v == "True" || v == "1"
 }
 
 func contains(l []string, s string) bool {
@@ -0,0 +1,51 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+
+	"helm.sh/helm/v3/pkg/postrender"
+)
+
+// Chain composes an ordered list of postrender.PostRenderer steps into a
+// single one, feeding each step's output to the next so a Manager can run
+// a Kustomize overlay, then any operator-supplied decorators, then owner
+// reference injection, as one PostRenderer handed to Helm.
+type Chain struct {
+	renderers []postrender.PostRenderer
+}
+
+// assert interface
+var _ postrender.PostRenderer = &Chain{}
+
+// NewChain returns a Chain that runs renderers in order.
+func NewChain(renderers ...postrender.PostRenderer) *Chain {
+	return &Chain{renderers: renderers}
+}
+
+// Run passes renderedManifests through every renderer in the chain, in
+// order, returning the last renderer's output.
+func (c *Chain) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	out := renderedManifests
+	for _, r := range c.renderers {
+		var err error
+		out, err = r.Run(out)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package engine provides Helm v3 post-render hooks used by the release
+// manager to stamp rendered manifests with ownership metadata before they
+// are applied to the cluster.
+package engine
+
+import (
+	"bytes"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/postrender"
+)
+
+// OwnerRefPostRenderer implements Helm v3's postrender.PostRenderer,
+// stamping every YAML document in the rendered manifest with the given
+// owner references after Helm has finished templating the chart.
+type OwnerRefPostRenderer struct {
+	refs []metav1.OwnerReference
+}
+
+// assert interface
+var _ postrender.PostRenderer = &OwnerRefPostRenderer{}
+
+// NewOwnerRefPostRenderer creates a post-renderer that adds the given
+// owner references to every object in the rendered manifest.
+func NewOwnerRefPostRenderer(refs []metav1.OwnerReference) postrender.PostRenderer {
+	return &OwnerRefPostRenderer{refs: refs}
+}
+
+// Run adds the configured owner references to every document in
+// renderedManifests, skipping empty documents produced by conditional
+// templates.
+func (o *OwnerRefPostRenderer) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	out := &bytes.Buffer{}
+	decoder := yamlDocumentDecoder(renderedManifests.Bytes())
+	for {
+		doc, ok := decoder()
+		if !ok {
+			break
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		withOwner, err := o.addOwnerRefs(doc)
+		if err != nil {
+			return nil, err
+		}
+		if withOwner == nil {
+			continue
+		}
+		out.WriteString("---\n")
+		out.Write(withOwner)
+	}
+	return out, nil
+}
+
+// addOwnerRefs adds the configured ownerRefs to a single rendered document.
+func (o *OwnerRefPostRenderer) addOwnerRefs(doc []byte) ([]byte, error) {
+	var parsed map[string]interface{}
+	if err := yaml.Unmarshal(doc, &parsed); err != nil {
+		return nil, fmt.Errorf("error parsing rendered template to add ownerrefs: %v", err)
+	}
+	if len(parsed) == 0 {
+		return nil, nil
+	}
+
+	unst, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&parsed)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: unst}
+	u.SetOwnerReferences(o.refs)
+	return yaml.Marshal(u.Object)
+}
+
+// yamlDocumentDecoder returns a closure that yields successive "---"
+// delimited documents from b on each call, and (nil, false) once exhausted.
+func yamlDocumentDecoder(b []byte) func() ([]byte, bool) {
+	docs := bytes.Split(b, []byte("\n---"))
+	i := 0
+	return func() ([]byte, bool) {
+		if i >= len(docs) {
+			return nil, false
+		}
+		doc := docs[i]
+		i++
+		return doc, true
+	}
+}
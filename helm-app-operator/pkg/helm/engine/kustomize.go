@@ -0,0 +1,154 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"helm.sh/helm/v3/pkg/postrender"
+	"sigs.k8s.io/kustomize/api/filesys"
+	"sigs.k8s.io/kustomize/api/krusty"
+)
+
+// KustomizeOverlayAnnotation, when set on a watched custom resource, names
+// a directory under the chart's "kustomize/" directory to use as the
+// kustomization base instead of the chart directory itself, so one chart
+// can ship several overlays (e.g. per environment) and have the CR pick
+// one.
+const KustomizeOverlayAnnotation = "helm.operator-framework.io/kustomize-overlay"
+
+// renderedManifestFile is the name KustomizeEngine stages Helm's rendered
+// output under, inside the kustomization base directory, so the base's
+// kustomization.yaml can list it as a resource (e.g. "resources:
+// [helm-rendered.yaml]") alongside any patches, prefixes, or labels it
+// wants to apply to it.
+const renderedManifestFile = "helm-rendered.yaml"
+
+// KustomizeEngine is a postrender.PostRenderer that runs a Kustomize
+// overlay against Helm's rendered manifest, applying whatever patches,
+// name prefixes, common labels, and image transforms the overlay's
+// kustomization.yaml declares.
+//
+// chartDir is the chart's resolved local path. If chartDir has no
+// kustomization.yaml (and overlay names a directory under chartDir/kustomize
+// with none either), Run passes the rendered manifest through unchanged,
+// so charts that don't use Kustomize pay no cost. A packaged .tgz chart
+// path has no such file beside it, so charts resolved to an archive are
+// always passed through unchanged.
+type KustomizeEngine struct {
+	chartDir string
+	overlay  string
+}
+
+// assert interface
+var _ postrender.PostRenderer = &KustomizeEngine{}
+
+// NewKustomizeEngine returns a KustomizeEngine that looks for a
+// kustomization.yaml in chartDir, or, if overlay is non-empty, in
+// chartDir/kustomize/overlay.
+func NewKustomizeEngine(chartDir, overlay string) *KustomizeEngine {
+	return &KustomizeEngine{chartDir: chartDir, overlay: overlay}
+}
+
+// Run stages renderedManifests as renderedManifestFile alongside a copy of
+// the kustomization base, in a temp directory, and runs krusty against that
+// copy, returning the result. If the base has no kustomization.yaml,
+// renderedManifests is returned as-is.
+func (k *KustomizeEngine) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	base := k.chartDir
+	if k.overlay != "" {
+		base = filepath.Join(k.chartDir, "kustomize", k.overlay)
+	}
+
+	fSys := filesys.MakeFsOnDisk()
+	if !fSys.Exists(filepath.Join(base, "kustomization.yaml")) {
+		return renderedManifests, nil
+	}
+
+	// The base is copied into a temp directory rather than staged in
+	// place, since the chart directory may be baked read-only into the
+	// operator image (the standard hardened deployment pattern), and
+	// writing into it would race another reconcile that resolved the same
+	// chart directory at the same time.
+	stageDir, err := ioutil.TempDir("", "helm-kustomize-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kustomize staging directory: %s", err)
+	}
+	defer os.RemoveAll(stageDir)
+
+	if err := copyDir(base, stageDir); err != nil {
+		return nil, fmt.Errorf("failed to stage kustomize overlay %s: %s", base, err)
+	}
+
+	stagedPath := filepath.Join(stageDir, renderedManifestFile)
+	if err := fSys.WriteFile(stagedPath, renderedManifests.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to stage rendered manifest for kustomize overlay %s: %s", base, err)
+	}
+
+	kustomizer := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	resMap, err := kustomizer.Run(fSys, stageDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run kustomize overlay %s: %s", base, err)
+	}
+
+	out, err := resMap.AsYaml()
+	if err != nil {
+		return nil, fmt.Errorf("failed to render kustomize overlay %s: %s", base, err)
+	}
+	return bytes.NewBuffer(out), nil
+}
+
+// copyDir recursively copies src's contents into dst, which must already
+// exist, preserving relative paths so a kustomization.yaml's relative
+// resource/patch references keep resolving against the copy.
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode())
+		}
+		return copyFile(path, target, info.Mode())
+	})
+}
+
+// copyFile copies src to dst, creating dst with the given mode.
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
@@ -0,0 +1,77 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics holds the Prometheus collectors shared by the helm
+// controller and release packages, registered against controller-runtime's
+// metrics registry so they're served alongside the rest of the manager's
+// metrics without the caller having to wire up its own HTTP handler.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// ReconcileTotal counts reconciles per watched GVK, labeled by whether
+	// the reconcile succeeded or returned an error.
+	ReconcileTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "helm_operator_reconcile_total",
+		Help: "Number of reconciles performed for a given GVK, by result",
+	}, []string{"gvk", "result"})
+
+	// ReconcileDurationSeconds tracks how long a single reconcile took,
+	// per watched GVK.
+	ReconcileDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "helm_operator_reconcile_duration_seconds",
+		Help:    "Duration of a single reconcile, in seconds, by GVK",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"gvk"})
+
+	// ReleaseTotal counts Helm release actions performed for a given GVK,
+	// labeled by which action (install, upgrade, uninstall, rollback) was
+	// taken.
+	ReleaseTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "helm_operator_release_total",
+		Help: "Number of Helm release actions performed for a given GVK, by action",
+	}, []string{"gvk", "action"})
+
+	// ReleaseInfo is a gauge of 1 per currently known release, carrying its
+	// version and status as labels so they can be queried/alerted on
+	// without scraping the release Secrets directly. Any previous series
+	// for a given gvk/name/namespace is deleted before a new one is set,
+	// since the version/status labels themselves change over a release's
+	// lifetime.
+	ReleaseInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "helm_operator_release_info",
+		Help: "Information about the current release for a CR, with value 1",
+	}, []string{"gvk", "name", "namespace", "version", "status"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		ReconcileTotal,
+		ReconcileDurationSeconds,
+		ReleaseTotal,
+		ReleaseInfo,
+	)
+}
+
+// SetReleaseInfo records the current version/status of the release owned
+// by the given CR, clearing any previously reported version/status pair
+// for that CR so stale series don't linger once a release moves on.
+func SetReleaseInfo(gvk, name, namespace, version, status string) {
+	ReleaseInfo.DeletePartialMatch(prometheus.Labels{"gvk": gvk, "name": name, "namespace": namespace})
+	ReleaseInfo.WithLabelValues(gvk, name, namespace, version, status).Set(1)
+}
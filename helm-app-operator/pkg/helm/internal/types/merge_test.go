@@ -0,0 +1,77 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import "testing"
+
+func TestMergeIntoPreservesUnknownKeys(t *testing.T) {
+	existing := map[string]interface{}{
+		"phase":           "Applied",
+		"customField":     "set-by-an-extension",
+		"anotherExternal": map[string]interface{}{"nested": true},
+	}
+	s := &HelmAppStatus{Phase: PhaseFailed, Reason: ReasonApplyFailed, Message: "boom"}
+
+	merged, err := s.MergeInto(existing)
+	if err != nil {
+		t.Fatalf("MergeInto returned error: %s", err)
+	}
+
+	if merged["phase"] != string(PhaseFailed) {
+		t.Errorf("merged[phase] = %v, want %v (owned key should be overwritten)", merged["phase"], PhaseFailed)
+	}
+	if merged["customField"] != "set-by-an-extension" {
+		t.Errorf("merged[customField] = %v, want it preserved from existing", merged["customField"])
+	}
+	if _, ok := merged["anotherExternal"]; !ok {
+		t.Error("merged should still contain anotherExternal, an unowned key")
+	}
+}
+
+func TestMergeIntoNilExisting(t *testing.T) {
+	s := &HelmAppStatus{Phase: PhaseApplied}
+	merged, err := s.MergeInto(nil)
+	if err != nil {
+		t.Fatalf("MergeInto returned error: %s", err)
+	}
+	if merged["phase"] != string(PhaseApplied) {
+		t.Errorf("merged[phase] = %v, want %v", merged["phase"], PhaseApplied)
+	}
+}
+
+func TestMergeIntoOverwritesEveryOwnedField(t *testing.T) {
+	existing := map[string]interface{}{}
+	for field := range ownedStatusFields {
+		existing[field] = "stale-value-from-a-previous-write"
+	}
+	existing["untouchedByUs"] = "should survive"
+
+	s := &HelmAppStatus{}
+	s.SetPhase(PhaseApplied, ReasonApplySuccessful, "")
+	s.SetResources(map[string]ResourceRef{}, true)
+
+	merged, err := s.MergeInto(existing)
+	if err != nil {
+		t.Fatalf("MergeInto returned error: %s", err)
+	}
+	for field := range ownedStatusFields {
+		if merged[field] == "stale-value-from-a-previous-write" {
+			t.Errorf("owned field %q was not overwritten by MergeInto", field)
+		}
+	}
+	if merged["untouchedByUs"] != "should survive" {
+		t.Error("unowned key untouchedByUs should have survived MergeInto")
+	}
+}
@@ -0,0 +1,348 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package types holds the status representation used by the generic,
+// watch-based Helm operator. It operates on arbitrary unstructured custom
+// resources, since a single operator binary may watch many different GVKs
+// (one per watches.yaml entry).
+package types
+
+import (
+	"encoding/json"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+type ResourcePhase string
+
+const (
+	PhaseNone     ResourcePhase = ""
+	PhaseApplying ResourcePhase = "Applying"
+	PhaseApplied  ResourcePhase = "Applied"
+	PhaseFailed   ResourcePhase = "Failed"
+)
+
+type ConditionReason string
+
+const (
+	ReasonUnknown               ConditionReason = "Unknown"
+	ReasonCustomResourceAdded   ConditionReason = "CustomResourceAdded"
+	ReasonCustomResourceUpdated ConditionReason = "CustomResourceUpdated"
+	ReasonApplySuccessful       ConditionReason = "ApplySuccessful"
+	ReasonApplyFailed           ConditionReason = "ApplyFailed"
+	ReasonRollbackSuccessful    ConditionReason = "RollbackSuccessful"
+	ReasonRollbackFailed        ConditionReason = "RollbackFailed"
+	ReasonValidationFailed      ConditionReason = "ValidationFailed"
+	// ReasonHookFailed distinguishes a ConditionReleaseFailed caused by a
+	// failed Helm hook (the release's manifest was still applied) from
+	// one caused by the apply itself failing.
+	ReasonHookFailed    ConditionReason = "HookFailed"
+	ReasonHookSucceeded ConditionReason = "HookSucceeded"
+	// ReasonWorkloadsReady and ReasonWorkloadsNotReady are the reasons
+	// set on ConditionReady.
+	ReasonWorkloadsReady    ConditionReason = "WorkloadsReady"
+	ReasonWorkloadsNotReady ConditionReason = "WorkloadsNotReady"
+)
+
+// ConditionType is the type of a HelmAppCondition, following the
+// Kubernetes convention of a small, closed set of well-known condition
+// types rather than a single opaque phase enum.
+type ConditionType string
+
+const (
+	// ConditionInitialized indicates whether the release's chart and
+	// values were successfully loaded and rendered.
+	ConditionInitialized ConditionType = "Initialized"
+	// ConditionDeployed indicates whether the release is currently
+	// installed and up to date in the cluster.
+	ConditionDeployed ConditionType = "Deployed"
+	// ConditionReleaseFailed indicates that the most recent install,
+	// upgrade, or uninstall attempt failed.
+	ConditionReleaseFailed ConditionType = "ReleaseFailed"
+	// ConditionIrreconcilable indicates that the last known-good release
+	// manifest could not be reconciled against the live cluster state.
+	ConditionIrreconcilable ConditionType = "Irreconcilable"
+	// ConditionRolledBack indicates that a failed upgrade was
+	// automatically rolled back to the last deployed revision.
+	ConditionRolledBack ConditionType = "Rolledback"
+	// ConditionReady indicates whether every workload owned by the
+	// release (Deployments, StatefulSets, DaemonSets, Jobs, Pods) reports
+	// its expected replica count, unlike ConditionDeployed, which only
+	// means the release's manifest was submitted. Gate on this condition
+	// with `kubectl wait --for=condition=Ready`.
+	ConditionReady ConditionType = "Ready"
+)
+
+// ConditionStatus is the tri-state value of a HelmAppCondition, mirroring
+// corev1.ConditionStatus.
+type ConditionStatus string
+
+const (
+	ConditionStatusTrue    ConditionStatus = "True"
+	ConditionStatusFalse   ConditionStatus = "False"
+	ConditionStatusUnknown ConditionStatus = "Unknown"
+)
+
+// HelmAppCondition describes one aspect of a release's current lifecycle
+// state, analogous to the conditions array used by Deployments and other
+// built-in Kubernetes resources.
+type HelmAppCondition struct {
+	Type               ConditionType    `json:"type"`
+	Status             ConditionStatus  `json:"status"`
+	Reason             ConditionReason  `json:"reason,omitempty"`
+	Message            string           `json:"message,omitempty"`
+	LastTransitionTime metav1.Time      `json:"lastTransitionTime,omitempty"`
+	// Release is the release known to be deployed as of this condition.
+	// Only set on the ConditionDeployed condition, so that a client keying
+	// off status.conditions[?(@.type=="Deployed")] can see which release
+	// the condition describes without also reading the (unconditionally
+	// overwritten) top-level Release field.
+	Release *release.Release `json:"release,omitempty"`
+}
+
+// HookStatus reports one Helm hook's last execution, as last returned in
+// a release's Hooks list after an install or upgrade.
+type HookStatus struct {
+	Name    string              `json:"name"`
+	Kind    string              `json:"kind"`
+	Events  []release.HookEvent `json:"events,omitempty"`
+	LastRun metav1.Time         `json:"lastRun,omitempty"`
+	Phase   release.HookPhase   `json:"phase"`
+}
+
+// ResourceRef identifies one object the release manifest produced, along
+// with its last observed readiness. Status-less kinds (Role, RoleBinding,
+// ClusterRole, ClusterRoleBinding, PodSecurityPolicy, ConfigMap, Secret,
+// Service, ...) are always reported as "Ready", since they have no
+// meaningful ready state of their own.
+type ResourceRef struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	Status     string `json:"status,omitempty"`
+}
+
+// HelmAppStatus represents the status of a watched custom resource that
+// is being reconciled as a Helm release.
+type HelmAppStatus struct {
+	Release    *release.Release   `json:"release"`
+	Conditions []HelmAppCondition `json:"conditions,omitempty"`
+	// Hooks reports the release's hooks as of the last install or
+	// upgrade, so a failed post-install/post-upgrade hook is visible on
+	// the resource without kubectl exec into the operator.
+	Hooks []HookStatus `json:"hooks,omitempty"`
+	// Resources inventories every object the release manifest produced,
+	// keyed by "<kind>/<namespace>/<name>" ("<kind>/<name>" for
+	// cluster-scoped objects).
+	Resources map[string]ResourceRef `json:"resources,omitempty"`
+	// Ready rolls Resources' readiness up into a single bool: true only
+	// once every workload among them reports its expected replica count.
+	// Mirrored onto ConditionReady for `kubectl wait --for=condition=Ready`.
+	Ready bool `json:"ready"`
+
+	// Phase, Reason, Message, LastUpdateTime, and LastTransitionTime are
+	// the pre-Conditions status representation. They're kept, rather than
+	// removed outright, so that clients which haven't migrated to
+	// status.conditions keep working; SyncPhaseFromConditions derives
+	// them from Conditions so callers only need to maintain one model
+	// going forward. New code should prefer SetCondition/GetCondition.
+	Phase              ResourcePhase   `json:"phase"`
+	Reason             ConditionReason `json:"reason,omitempty"`
+	Message            string          `json:"message,omitempty"`
+	LastUpdateTime     metav1.Time     `json:"lastUpdateTime,omitempty"`
+	LastTransitionTime metav1.Time     `json:"lastTransitionTime,omitempty"`
+}
+
+func (s *HelmAppStatus) ToMap() (map[string]interface{}, error) {
+	var out map[string]interface{}
+	jsonObj, err := json.Marshal(&s)
+	if err != nil {
+		return nil, err
+	}
+	json.Unmarshal(jsonObj, &out)
+	return out, nil
+}
+
+// ownedStatusFields are the status subresource keys this package writes.
+// MergeInto overwrites only these, so it never clobbers a key set by an
+// external controller or a ReconcileExtension.
+var ownedStatusFields = map[string]bool{
+	"release":            true,
+	"phase":              true,
+	"reason":             true,
+	"message":            true,
+	"conditions":         true,
+	"hooks":              true,
+	"resources":          true,
+	"ready":              true,
+	"lastUpdateTime":     true,
+	"lastTransitionTime": true,
+}
+
+// MergeInto overlays s onto existing, the status subresource map as last
+// read from the cluster (which may hold keys this package doesn't own),
+// returning the merged map to write back. existing may be nil.
+func (s *HelmAppStatus) MergeInto(existing map[string]interface{}) (map[string]interface{}, error) {
+	merged, err := s.ToMap()
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range existing {
+		if ownedStatusFields[k] {
+			continue
+		}
+		merged[k] = v
+	}
+	return merged, nil
+}
+
+// SetPhase takes a custom resource status and returns the updated status, without updating the resource in the cluster.
+func (s *HelmAppStatus) SetPhase(phase ResourcePhase, reason ConditionReason, message string) *HelmAppStatus {
+	s.LastUpdateTime = metav1.Now()
+	if s.Phase != phase {
+		s.Phase = phase
+		s.LastTransitionTime = metav1.Now()
+	}
+	s.Message = message
+	s.Reason = reason
+	return s
+}
+
+// SetRelease takes a release object and adds or updates the release on the status object
+func (s *HelmAppStatus) SetRelease(release *release.Release) *HelmAppStatus {
+	s.Release = release
+	return s
+}
+
+// SetHooks records the current release's hooks on the status, converting
+// Helm's release.Hook (which also carries the hook's full manifest) down
+// to the name/kind/events/phase summary worth persisting on the resource.
+func (s *HelmAppStatus) SetHooks(hooks []*release.Hook) *HelmAppStatus {
+	hookStatuses := make([]HookStatus, 0, len(hooks))
+	for _, h := range hooks {
+		hookStatuses = append(hookStatuses, HookStatus{
+			Name:    h.Name,
+			Kind:    h.Kind,
+			Events:  h.Events,
+			LastRun: h.LastRun.StartedAt,
+			Phase:   h.LastRun.Phase,
+		})
+	}
+	s.Hooks = hookStatuses
+	return s
+}
+
+// SetResources records the release's resource inventory and its rolled-up
+// readiness, and mirrors ready onto ConditionReady.
+func (s *HelmAppStatus) SetResources(resources map[string]ResourceRef, ready bool) *HelmAppStatus {
+	s.Resources = resources
+	s.Ready = ready
+	conditionStatus := ConditionStatusFalse
+	reason := ReasonWorkloadsNotReady
+	if ready {
+		conditionStatus = ConditionStatusTrue
+		reason = ReasonWorkloadsReady
+	}
+	return s.SetCondition(HelmAppCondition{
+		Type:   ConditionReady,
+		Status: conditionStatus,
+		Reason: reason,
+	})
+}
+
+// SetCondition adds or updates a condition of the given type, bumping
+// LastTransitionTime only when the condition's status actually changes.
+func (s *HelmAppStatus) SetCondition(condition HelmAppCondition) *HelmAppStatus {
+	for i, existing := range s.Conditions {
+		if existing.Type != condition.Type {
+			continue
+		}
+		if existing.Status == condition.Status {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		} else if condition.LastTransitionTime.IsZero() {
+			condition.LastTransitionTime = metav1.Now()
+		}
+		s.Conditions[i] = condition
+		return s
+	}
+	if condition.LastTransitionTime.IsZero() {
+		condition.LastTransitionTime = metav1.Now()
+	}
+	s.Conditions = append(s.Conditions, condition)
+	return s
+}
+
+// GetCondition returns the condition of the given type, or nil if it has
+// never been set.
+func (s *HelmAppStatus) GetCondition(t ConditionType) *HelmAppCondition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == t {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// RemoveCondition removes the condition of the given type, if present. It's
+// a no-op if the condition has never been set.
+func (s *HelmAppStatus) RemoveCondition(t ConditionType) *HelmAppStatus {
+	for i, existing := range s.Conditions {
+		if existing.Type == t {
+			s.Conditions = append(s.Conditions[:i], s.Conditions[i+1:]...)
+			break
+		}
+	}
+	return s
+}
+
+// SyncPhaseFromConditions derives the legacy Phase/Reason/Message fields
+// from the current Conditions, so a caller that only maintains Conditions
+// still populates the deprecated flat fields for clients that haven't
+// migrated. ConditionReleaseFailed takes priority over ConditionDeployed,
+// since a release can be marked Deployed from a prior revision while the
+// most recent install/upgrade attempt failed.
+func (s *HelmAppStatus) SyncPhaseFromConditions() *HelmAppStatus {
+	if c := s.GetCondition(ConditionReleaseFailed); c != nil && c.Status == ConditionStatusTrue {
+		return s.SetPhase(PhaseFailed, c.Reason, c.Message)
+	}
+	if c := s.GetCondition(ConditionDeployed); c != nil && c.Status == ConditionStatusTrue {
+		return s.SetPhase(PhaseApplied, c.Reason, c.Message)
+	}
+	return s
+}
+
+// StatusFor safely returns a typed status block from a custom resource.
+func StatusFor(cr *unstructured.Unstructured) *HelmAppStatus {
+	switch cr.Object["status"].(type) {
+	case HelmAppStatus:
+		return cr.Object["status"].(*HelmAppStatus)
+	case map[string]interface{}:
+		var status *HelmAppStatus
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(cr.Object["status"].(map[string]interface{}), &status); err != nil {
+			return &HelmAppStatus{
+				Phase:   PhaseFailed,
+				Reason:  ReasonApplyFailed,
+				Message: err.Error(),
+			}
+		}
+		return status
+	default:
+		return &HelmAppStatus{}
+	}
+}
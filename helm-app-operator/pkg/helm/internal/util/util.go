@@ -0,0 +1,69 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package util holds small helpers shared across the release and
+// controller packages.
+package util
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// ResourceString returns a human friendly string for the custom resource
+func ResourceString(r *unstructured.Unstructured) string {
+	return fmt.Sprintf("apiVersion=%s kind=%s name=%s/%s", r.GetAPIVersion(), r.GetKind(), r.GetNamespace(), r.GetName())
+}
+
+// Diff returns a unified diff between the two manifests, suitable for
+// logging at debug level.
+func Diff(before, after string) string {
+	diff := difflib.UnifiedDiff{
+		A:        difflib.SplitLines(before),
+		B:        difflib.SplitLines(after),
+		FromFile: "before",
+		ToFile:   "after",
+		Context:  3,
+	}
+	out, err := difflib.GetUnifiedDiffString(diff)
+	if err != nil {
+		return fmt.Sprintf("failed to compute diff: %s", err)
+	}
+	return out
+}
+
+// ManifestObjects parses a rendered Helm manifest (one or more "---"
+// delimited YAML documents) into the objects it describes. Empty
+// documents, which conditional templates commonly produce, are skipped.
+func ManifestObjects(manifest string) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+	for _, doc := range bytes.Split([]byte(manifest), []byte("\n---")) {
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		u := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(doc, &u.Object); err != nil {
+			return nil, fmt.Errorf("failed to parse manifest document: %s", err)
+		}
+		if len(u.Object) == 0 {
+			continue
+		}
+		objs = append(objs, u)
+	}
+	return objs, nil
+}
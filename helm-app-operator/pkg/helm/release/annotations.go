@@ -0,0 +1,55 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+// rollbackOnFailureAnnotation, when set to "true" on a watched custom
+// resource, causes UpdateRelease to automatically roll back to the last
+// DEPLOYED revision if the upgrade fails, rather than leaving the release
+// in a FAILED state.
+const rollbackOnFailureAnnotation = "helm.operator-sdk/rollback-on-failure"
+
+// valuesFromAnnotation names a YAML list of ConfigMap/Secret references
+// whose data is merged into the CR's values, in the same spirit as a Pod's
+// envFrom. Each entry is either:
+//
+//	- configMapRef: {name: my-common-values}
+//	- secretRef:    {name: my-common-secrets, optional: true}
+//
+// The referenced object must live in the CR's namespace. Every key in its
+// data is merged into the values tree; a key named "values.yaml" is parsed
+// as a YAML values fragment and merged at the top level, while any other
+// key is parsed as a single YAML scalar/mapping and merged under a value
+// path matching the key name.
+const valuesFromAnnotation = "helm.operator-sdk/values-from"
+
+// setAnnotation names a comma-separated list of key=value pairs using
+// Helm's --set syntax (parsed with strvals), applied after valuesFrom.
+const setAnnotation = "helm.operator-sdk/set"
+
+// upgradeForceAnnotation, when set to "true" on a watched custom resource,
+// causes UpdateRelease to force the upgrade, recreating any resource whose
+// update can't be patched in place instead of failing the upgrade.
+const upgradeForceAnnotation = "helm.sdk.operatorframework.io/upgrade-force"
+
+// UninstallWaitAnnotation, when set to "true" on a watched custom
+// resource, tells HelmOperatorReconciler to hold the uninstall-helm-release
+// finalizer until every resource in the uninstalled release's manifest has
+// actually disappeared from the API server, rather than releasing it as
+// soon as the release itself is removed from storage.
+const UninstallWaitAnnotation = "helm.sdk.operatorframework.io/uninstall-wait"
+
+func isTruthy(v string) bool {
+	return v == "true" || v == "True" || v == "1"
+}
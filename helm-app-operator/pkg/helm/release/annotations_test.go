@@ -0,0 +1,54 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import "testing"
+
+func TestIsTruthy(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want bool
+	}{
+		{"true lowercase", "true", true},
+		{"True titlecase", "True", true},
+		{"one", "1", true},
+		{"empty", "", false},
+		{"false", "false", false},
+		{"yes is not recognized", "yes", false},
+		{"uppercase TRUE is not recognized", "TRUE", false},
+		{"zero", "0", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := isTruthy(c.in); got != c.want {
+				t.Errorf("isTruthy(%q) = %v, want %v", c.in, got, c.want)
+			}
+		})
+	}
+}
+
+// TestUpgradeForceAndUninstallWaitAnnotations pins the exact annotation
+// keys upgradeForceAnnotation and UninstallWaitAnnotation are read from, so
+// a rename of either constant is caught here rather than only showing up as
+// a silently-ignored annotation on a live CR.
+func TestUpgradeForceAndUninstallWaitAnnotations(t *testing.T) {
+	if upgradeForceAnnotation != "helm.sdk.operatorframework.io/upgrade-force" {
+		t.Errorf("upgradeForceAnnotation = %q, want %q", upgradeForceAnnotation, "helm.sdk.operatorframework.io/upgrade-force")
+	}
+	if UninstallWaitAnnotation != "helm.sdk.operatorframework.io/uninstall-wait" {
+		t.Errorf("UninstallWaitAnnotation = %q, want %q", UninstallWaitAnnotation, "helm.sdk.operatorframework.io/uninstall-wait")
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// defaultChartCacheDir is where charts fetched from a remote source (an
+// archive URL, a chart repository, or an OCI registry) are cached on disk,
+// keyed by the sha256 digest of the downloaded archive. Every call to
+// Resolve for a remote source downloads the chart again; the digest is only
+// known after the download, so the cache does not avoid a fetch. It lets
+// repeated downloads of the same archive share one file on disk.
+const defaultChartCacheDir = "/tmp/helm-chart-cache"
+
+// defaultChartCacheTTL bounds how long a cached chart archive is kept
+// around. It's checked opportunistically, on every cache write, rather
+// than on a separate timer, so idle watches don't grow the cache forever
+// on an operator that's never restarted.
+const defaultChartCacheTTL = 24 * time.Hour
+
+// RepoAuth holds HTTP basic-auth credentials for a private chart
+// repository index or packaged-archive URL. It has no effect on oci://
+// charts, which authenticate through the ambient Docker credential store
+// instead.
+type RepoAuth struct {
+	Username string
+	Password string
+}
+
+// ChartResolver turns a watch entry's chart reference into a local path
+// that chartutil.LoadDir or loader.Load can consume, fetching and caching
+// the chart first if it isn't already on disk.
+//
+// A chart reference is one of:
+//   - a local chart directory (validated with chartutil.IsChartDir)
+//   - a local or remote packaged chart archive (a path or http(s):// URL
+//     ending in .tgz)
+//   - an oci:// reference to a chart stored in an OCI registry
+//   - a repo+name+version triple resolved against a Helm chart repository
+//     index
+type ChartResolver struct {
+	cacheDir string
+	cacheTTL time.Duration
+
+	// Auth, if set, is sent as HTTP basic auth on every repository index
+	// and archive download this resolver performs.
+	Auth *RepoAuth
+}
+
+// NewChartResolver returns a ChartResolver that caches downloaded charts
+// under cacheDir. If cacheDir is empty, defaultChartCacheDir is used.
+func NewChartResolver(cacheDir string) *ChartResolver {
+	if cacheDir == "" {
+		cacheDir = defaultChartCacheDir
+	}
+	return &ChartResolver{cacheDir: cacheDir, cacheTTL: defaultChartCacheTTL}
+}
+
+// Resolve returns a local filesystem path for the chart described by w,
+// downloading and caching it first if necessary.
+func (r *ChartResolver) Resolve(w watch) (string, error) {
+	switch {
+	case w.Repo != "":
+		if w.Name == "" {
+			return "", fmt.Errorf("watch for repo %q must set a chart name", w.Repo)
+		}
+		return r.resolveFromRepo(w.Repo, w.Name, w.ChartVersion)
+
+	case strings.HasPrefix(w.Chart, "oci://"):
+		return r.resolveFromOCI(w.Chart, w.SHA256)
+
+	case strings.HasPrefix(w.Chart, "http://"), strings.HasPrefix(w.Chart, "https://"):
+		return r.resolveFromURL(w.Chart, w.SHA256, w.Provenance)
+
+	case strings.HasSuffix(w.Chart, ".tgz"):
+		if _, err := os.Stat(w.Chart); err != nil {
+			return "", fmt.Errorf("chart archive %q not found: %s", w.Chart, err)
+		}
+		if w.SHA256 != "" {
+			if err := verifyFileDigest(w.Chart, w.SHA256); err != nil {
+				return "", err
+			}
+		}
+		return w.Chart, nil
+
+	default:
+		if _, err := chartutil.IsChartDir(w.Chart); err != nil {
+			return "", fmt.Errorf("invalid chart directory %s: %s", w.Chart, err)
+		}
+		return w.Chart, nil
+	}
+}
+
+// resolveFromURL downloads a packaged chart from an http(s) URL into the
+// cache, keyed by the digest of its contents, verifying it against sha256
+// and/or a detached provenance file when provided.
+func (r *ChartResolver) resolveFromURL(url, sha256sum, provenanceURL string) (string, error) {
+	body, err := httpGet(url, r.Auth)
+	if err != nil {
+		return "", fmt.Errorf("failed to download chart %s: %s", url, err)
+	}
+	if sha256sum != "" {
+		if err := verifyDigest(body, sha256sum); err != nil {
+			return "", err
+		}
+	}
+	if provenanceURL != "" {
+		if err := r.verifyProvenance(body, provenanceURL); err != nil {
+			return "", err
+		}
+	}
+	return r.cacheArchive(body)
+}
+
+// resolveFromRepo resolves name/version against the index of a Helm chart
+// repository and downloads the matching chart archive. version may be a
+// semver constraint (e.g. "~1.2"), in which case the highest matching
+// version in the index is used.
+func (r *ChartResolver) resolveFromRepo(repoURL, name, version string) (string, error) {
+	indexURL := strings.TrimSuffix(repoURL, "/") + "/index.yaml"
+	indexBytes, err := httpGet(indexURL, r.Auth)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch repository index %s: %s", indexURL, err)
+	}
+
+	indexFile, err := loadIndexFile(indexBytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse repository index %s: %s", indexURL, err)
+	}
+
+	cv, err := indexFile.Get(name, version)
+	if err != nil {
+		return "", fmt.Errorf("failed to find chart %s version %q in repository %s: %s", name, version, repoURL, err)
+	}
+	if len(cv.URLs) == 0 {
+		return "", fmt.Errorf("chart %s version %s in repository %s has no download URLs", name, cv.Version, repoURL)
+	}
+
+	chartURL := resolveRelativeURL(repoURL, cv.URLs[0])
+	body, err := httpGet(chartURL, r.Auth)
+	if err != nil {
+		return "", fmt.Errorf("failed to download chart %s: %s", chartURL, err)
+	}
+	if cv.Digest != "" {
+		if err := verifyDigest(body, cv.Digest); err != nil {
+			return "", err
+		}
+	}
+	return r.cacheArchive(body)
+}
+
+// resolveFromOCI pulls a chart from an OCI registry reference of the form
+// oci://host/repo/chart:tag.
+func (r *ChartResolver) resolveFromOCI(ref, sha256sum string) (string, error) {
+	client, err := registry.NewClient()
+	if err != nil {
+		return "", fmt.Errorf("failed to create OCI registry client: %s", err)
+	}
+	result, err := client.Pull(strings.TrimPrefix(ref, "oci://"))
+	if err != nil {
+		return "", fmt.Errorf("failed to pull chart %s: %s", ref, err)
+	}
+	if sha256sum != "" {
+		if err := verifyDigest(result.Chart.Data, sha256sum); err != nil {
+			return "", err
+		}
+	}
+	return r.cacheArchive(result.Chart.Data)
+}
+
+// cacheArchive writes a downloaded chart archive into the cache directory,
+// keyed by its sha256 digest, and returns the cached path. If an archive
+// with the same digest is already cached, its access time is refreshed and
+// the download is reused as-is. Every write also evicts entries that have
+// aged out, so charts no longer referenced by any watch are eventually
+// cleaned up without needing a separate sweep.
+func (r *ChartResolver) cacheArchive(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+
+	if err := os.MkdirAll(r.cacheDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create chart cache directory %s: %s", r.cacheDir, err)
+	}
+	r.evictExpired()
+
+	cachedPath := filepath.Join(r.cacheDir, digest+".tgz")
+	now := time.Now()
+	if _, err := os.Stat(cachedPath); err == nil {
+		_ = os.Chtimes(cachedPath, now, now)
+		return cachedPath, nil
+	}
+	if err := ioutil.WriteFile(cachedPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write cached chart %s: %s", cachedPath, err)
+	}
+	return cachedPath, nil
+}
+
+// evictExpired removes cached chart archives whose modification time is
+// older than r.cacheTTL. Stat/remove errors for an individual entry are
+// ignored; a chart that fails to evict is just re-checked on the next
+// cache write.
+func (r *ChartResolver) evictExpired() {
+	if r.cacheTTL <= 0 {
+		return
+	}
+	entries, err := ioutil.ReadDir(r.cacheDir)
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().Add(-r.cacheTTL)
+	for _, entry := range entries {
+		if entry.IsDir() || entry.ModTime().After(cutoff) {
+			continue
+		}
+		_ = os.Remove(filepath.Join(r.cacheDir, entry.Name()))
+	}
+}
+
+// verifyProvenance fetches the detached provenance file for a chart archive
+// and confirms the sha256 digest it records matches the archive's actual
+// contents. It does not verify the PGP signature over the provenance file
+// itself, since doing so requires a configured keyring the operator has no
+// analogous mechanism for yet.
+func (r *ChartResolver) verifyProvenance(chartBytes []byte, provenanceURL string) error {
+	provBytes, err := httpGet(provenanceURL, r.Auth)
+	if err != nil {
+		return fmt.Errorf("failed to download provenance file %s: %s", provenanceURL, err)
+	}
+	sum := sha256.Sum256(chartBytes)
+	digest := hex.EncodeToString(sum[:])
+	if !strings.Contains(string(provBytes), digest) {
+		return fmt.Errorf("provenance file %s does not match chart digest %s", provenanceURL, digest)
+	}
+	return nil
+}
+
+func verifyDigest(data []byte, wantSHA256 string) error {
+	sum := sha256.Sum256(data)
+	got := hex.EncodeToString(sum[:])
+	want := strings.TrimPrefix(strings.ToLower(wantSHA256), "sha256:")
+	if got != want {
+		return fmt.Errorf("chart digest mismatch: want %s, got %s", want, got)
+	}
+	return nil
+}
+
+func verifyFileDigest(path, wantSHA256 string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %s", path, err)
+	}
+	return verifyDigest(data, wantSHA256)
+}
+
+func loadIndexFile(b []byte) (*repo.IndexFile, error) {
+	i := &repo.IndexFile{}
+	if err := yaml.Unmarshal(b, i); err != nil {
+		return nil, err
+	}
+	i.SortEntries()
+	return i, nil
+}
+
+func httpGet(url string, auth *RepoAuth) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if auth != nil {
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// resolveRelativeURL resolves a chart URL found in a repository index
+// against the index's own base URL, matching Helm's own behavior for
+// indexes that list chart archives with paths relative to the repo root.
+func resolveRelativeURL(repoURL, chartURL string) string {
+	if strings.HasPrefix(chartURL, "http://") || strings.HasPrefix(chartURL, "https://") {
+		return chartURL
+	}
+	return strings.TrimSuffix(repoURL, "/") + "/" + strings.TrimPrefix(chartURL, "/")
+}
@@ -0,0 +1,72 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// restClientGetter adapts a controller-runtime manager.Manager to the
+// genericclioptions.RESTClientGetter interface required to build a Helm v3
+// action.Configuration.
+type restClientGetter struct {
+	restConfig      *rest.Config
+	discoveryClient discovery.CachedDiscoveryInterface
+	restMapper      meta.RESTMapper
+}
+
+// assert interface
+var _ genericclioptions.RESTClientGetter = &restClientGetter{}
+
+func (c *restClientGetter) ToRESTConfig() (*rest.Config, error) {
+	return c.restConfig, nil
+}
+
+func (c *restClientGetter) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	return c.discoveryClient, nil
+}
+
+func (c *restClientGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	return c.restMapper, nil
+}
+
+func (c *restClientGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
+	return nil
+}
+
+// newRESTClientGetterFromManager returns a RESTClientGetter backed by the
+// rest.Config and REST mapper already held by mgr, so the release package
+// doesn't need to build its own kubeconfig. Its discovery client is a new
+// in-memory cached client built from that same rest.Config.
+func newRESTClientGetterFromManager(mgr manager.Manager) (*restClientGetter, error) {
+	cfg := mgr.GetConfig()
+	dc, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cdc := memory.NewMemCacheClient(dc)
+
+	return &restClientGetter{
+		restConfig:      cfg,
+		discoveryClient: cdc,
+		restMapper:      mgr.GetRESTMapper(),
+	}, nil
+}
@@ -0,0 +1,37 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"context"
+
+	"helm.sh/helm/v3/pkg/action"
+	rpb "helm.sh/helm/v3/pkg/release"
+)
+
+// InstallRelease performs a Helm v3 install of the managed chart using the
+// values computed during Sync.
+func (m *manager) InstallRelease(ctx context.Context) (*rpb.Release, error) {
+	install := action.NewInstall(m.actionConfig)
+	install.ReleaseName = m.releaseName
+	install.Namespace = m.namespace
+	install.PostRenderer = m.postRenderer()
+
+	installedRelease, err := install.RunWithContext(ctx, m.chart, m.values)
+	if err != nil {
+		return nil, err
+	}
+	return installedRelease, nil
+}
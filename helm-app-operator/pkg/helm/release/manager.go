@@ -15,386 +15,443 @@
 package release
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
+	"io/ioutil"
+	"os"
+	"time"
 
-	"github.com/martinlindhe/base36"
-	"github.com/pborman/uuid"
-	"github.com/sirupsen/logrus"
+	"github.com/go-logr/logr"
 
 	yaml "gopkg.in/yaml.v2"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	apitypes "k8s.io/apimachinery/pkg/types"
-	"k8s.io/helm/pkg/chartutil"
-	helmengine "k8s.io/helm/pkg/engine"
-	"k8s.io/helm/pkg/kube"
-	cpb "k8s.io/helm/pkg/proto/hapi/chart"
-	"k8s.io/helm/pkg/proto/hapi/release"
-	rpb "k8s.io/helm/pkg/proto/hapi/release"
-	"k8s.io/helm/pkg/proto/hapi/services"
-	"k8s.io/helm/pkg/storage"
-	"k8s.io/helm/pkg/tiller"
-	"k8s.io/helm/pkg/tiller/environment"
-	"k8s.io/kubernetes/pkg/client/clientset_generated/internalclientset"
-	"k8s.io/kubernetes/pkg/kubectl/genericclioptions/resource"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/postrender"
+	rpb "helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"github.com/operator-framework/helm-app-operator-kit/helm-app-operator/pkg/helm/engine"
 	"github.com/operator-framework/helm-app-operator-kit/helm-app-operator/pkg/helm/internal/types"
 	"github.com/operator-framework/helm-app-operator-kit/helm-app-operator/pkg/helm/internal/util"
 )
 
+// ErrNotFound is returned by UninstallRelease when the managed release has
+// no history, i.e. it was never installed or has already been removed.
+var ErrNotFound = driver.ErrReleaseNotFound
+
+// ErrRolledBack wraps the original upgrade error when UpdateRelease
+// automatically rolled the release back to its last deployed revision
+// because the CR carries the rollbackOnFailureAnnotation. Callers can use
+// errors.Is(err, ErrRolledBack) to distinguish a recovered rollback from an
+// upgrade failure that left the release in a FAILED state.
+var ErrRolledBack = errors.New("release upgrade failed and was rolled back")
+
+// storageFactory builds the *storage.Storage backing a single CR's
+// release history, scoped to that CR's own namespace. Each Manager gets
+// its own Storage rather than sharing one cluster-wide store, which
+// enables multi-tenant operation, avoids the ConfigMap 1MB size limit for
+// large charts, and lets RBAC be scoped per namespace.
+type storageFactory func(namespace string) *storage.Storage
+
+// newStorageFactory returns a storageFactory that constructs a
+// Secrets-backed storage.Storage against namespace using clientset.
+func newStorageFactory(clientset kubernetes.Interface) storageFactory {
+	return func(namespace string) *storage.Storage {
+		return storage.Init(driver.NewSecrets(clientset.CoreV1().Secrets(namespace)))
+	}
+}
+
 // ManagerFactory creates Managers that are specific to custom resources. It is
 // used by the HelmOperatorReconciler during resource reconciliation, and it
 // improves decoupling between reconciliation logic and the Helm backend
 // components used to manage releases.
 type ManagerFactory interface {
-	NewManager(r *unstructured.Unstructured) Manager
+	// NewManager returns a Manager for r, whose release history is stored
+	// as Secrets in r's own namespace. It errors if r has no namespace to
+	// scope that storage to.
+	NewManager(r *unstructured.Unstructured) (Manager, error)
+
+	// ResyncPeriod returns the poll interval configured for this factory's
+	// watch entry, and whether one was set. Watches whose chart resolves
+	// against a floating version (a semver range against a repository, or
+	// an OCI tag that moves) use this to re-resolve and potentially
+	// reconcile more often than the operator's default resync period.
+	ResyncPeriod() (time.Duration, bool)
+
+	// ReconcileTimeout returns the per-reconcile timeout configured for
+	// this factory's watch entry, and whether one was set. A Reconciler
+	// uses this to bound how long a single Sync/Install/Update/Uninstall
+	// call may run before it's canceled.
+	ReconcileTimeout() (time.Duration, bool)
+
+	// HasStatusSubresource reports whether this factory's watch entry
+	// declared its CRD's status subresource, so a Reconciler knows
+	// whether to write status through the status subresource endpoint.
+	HasStatusSubresource() bool
 }
 
 type managerFactory struct {
-	storageBackend   *storage.Storage
-	tillerKubeClient *kube.Client
-	chartDir         string
+	mgr      manager.Manager
+	getter   *restClientGetter
+	storage  storageFactory
+	watch    watch
+	resolver *ChartResolver
+	log      logr.Logger
+
+	// extraPostRenderers run, in order, after the built-in KustomizeEngine
+	// step and before owner references are stamped onto the result. They're
+	// configured via WithPostRenderers and let a downstream operator extend
+	// the rendering chain (e.g. a jsonpatch overlay or a sops-decrypt step)
+	// without forking this package.
+	extraPostRenderers []postrender.PostRenderer
+
+	pollInterval         time.Duration
+	reconcileTimeout     time.Duration
+	hasStatusSubresource bool
 }
 
-func (f managerFactory) NewManager(r *unstructured.Unstructured) Manager {
-	return f.newManagerForCR(r)
-}
-
-func (f managerFactory) newManagerForCR(r *unstructured.Unstructured) Manager {
-	return &manager{
-		storageBackend:   f.storageBackend,
-		tillerKubeClient: f.tillerKubeClient,
-		chartDir:         f.chartDir,
-
-		tiller:      f.tillerRendererForCR(r),
-		releaseName: getReleaseName(r),
-		namespace:   r.GetNamespace(),
+// ManagerFactoryOption customizes a ManagerFactory at construction time.
+type ManagerFactoryOption func(*managerFactory)
 
-		resource: r,
-		spec:     r.Object["spec"],
-		status:   types.StatusFor(r),
+// WithPostRenderers appends renderers to the chain every Manager built by
+// this factory runs its chart through, after the built-in KustomizeEngine
+// step and before owner references are injected.
+func WithPostRenderers(renderers ...postrender.PostRenderer) ManagerFactoryOption {
+	return func(f *managerFactory) {
+		f.extraPostRenderers = append(f.extraPostRenderers, renderers...)
 	}
 }
 
-// Manager manages a Helm release. It can install, update, reconcile,
-// and uninstall a release.
-type Manager interface {
-	ReconcileRelease() (*rpb.Release, bool, error)
-	UninstallRelease() (*rpb.Release, error)
-}
-
-type manager struct {
-	storageBackend   *storage.Storage
-	tillerKubeClient *kube.Client
-	chartDir         string
-
-	tiller      *tiller.ReleaseServer
-	releaseName string
-	namespace   string
-
-	resource *unstructured.Unstructured
-	spec     interface{}
-	status   *types.HelmAppStatus
+// NewManagerFactory returns a ManagerFactory capable of building a Manager
+// for any custom resource, with release state stored via the Helm v3
+// Secrets driver in the CR's own namespace. chartDir must be a local chart
+// directory; use newManagerFactory for watch entries that reference a
+// remote chart source.
+func NewManagerFactory(mgr manager.Manager, chartDir string, opts ...ManagerFactoryOption) (ManagerFactory, error) {
+	return newManagerFactory(mgr, watch{Chart: chartDir}, opts...)
 }
 
-// ReconcileRelease ensures the managed release is reconciled,
-// and returns the updated release if successful (or an error otherwise).
-// - If the release is not already installed, a new release will be installed.
-// - If the release has changed, the release will be updated.
-// - If the release has no changes, the underlying resources will be reconciled.
-func (m manager) ReconcileRelease() (*rpb.Release, bool, error) {
-	needsUpdate := false
-
-	// chart is mutated by the call to processRequirements,
-	// so we need to reload it from disk every time.
-	chart, err := chartutil.LoadDir(m.chartDir)
-	if err != nil {
-		return nil, needsUpdate, fmt.Errorf("failed to load chart: %s", err)
-	}
-
-	cr, err := yaml.Marshal(m.spec)
+// newManagerFactory returns a ManagerFactory for the given watch entry,
+// resolving its chart (a local directory, a packaged archive, a repository
+// entry, or an OCI reference) once per reconcile via a ChartResolver so
+// that floating versions are re-checked on every Sync.
+func newManagerFactory(mgr manager.Manager, w watch, opts ...ManagerFactoryOption) (ManagerFactory, error) {
+	getter, err := newRESTClientGetterFromManager(mgr)
 	if err != nil {
-		return nil, needsUpdate, fmt.Errorf("failed to parse values: %s", err)
+		return nil, fmt.Errorf("failed to build REST client getter: %s", err)
 	}
-	config := &cpb.Config{Raw: string(cr)}
-	logrus.Debugf("Using values: %s", config.GetRaw())
 
-	err = processRequirements(chart, config)
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
 	if err != nil {
-		return nil, needsUpdate, fmt.Errorf("failed to process chart requirements: %s", err)
-	}
-
-	tiller := m.tiller
-
-	status := m.status
-	if err := m.syncReleaseStatus(*status); err != nil {
-		return nil, needsUpdate, fmt.Errorf("failed to sync release status: %s", err)
-	}
-
-	releaseName := m.releaseName
-
-	// Get release history for this release name
-	releases, err := m.storageBackend.History(releaseName)
-	if err != nil && !notFoundErr(err) {
-		return nil, needsUpdate, fmt.Errorf("failed to retrieve release history: %s", err)
+		return nil, fmt.Errorf("failed to build kubernetes clientset: %s", err)
 	}
 
-	// Cleanup non-deployed release versions. If all release versions are
-	// non-deployed, this will ensure that failed installations are correctly
-	// retried.
-	for _, rel := range releases {
-		if rel.GetInfo().GetStatus().GetCode() != release.Status_DEPLOYED {
-			_, err := m.storageBackend.Delete(rel.GetName(), rel.GetVersion())
-			if err != nil && !notFoundErr(err) {
-				return nil, needsUpdate, fmt.Errorf("failed to delete stale release version: %s", err)
-			}
+	var pollInterval time.Duration
+	if w.PollInterval != "" {
+		pollInterval, err = time.ParseDuration(w.PollInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pollInterval %q: %s", w.PollInterval, err)
 		}
 	}
 
-	var updatedRelease *release.Release
-	latestRelease, err := m.storageBackend.Deployed(releaseName)
-	if err != nil || latestRelease == nil {
-		// If there's no deployed release, attempt a tiller install.
-		updatedRelease, err = m.installRelease(tiller, m.namespace, releaseName, chart, config)
+	var reconcileTimeout time.Duration
+	if w.ReconcileTimeout != "" {
+		reconcileTimeout, err = time.ParseDuration(w.ReconcileTimeout)
 		if err != nil {
-			return nil, needsUpdate, fmt.Errorf("install error: %s", err)
+			return nil, fmt.Errorf("invalid reconcileTimeout %q: %s", w.ReconcileTimeout, err)
 		}
-		needsUpdate = true
-		diffStr := util.Diff("", updatedRelease.GetManifest())
-		logrus.Infof("Installed release for %s release=%s; diff:\n%s", util.ResourceString(m.resource), updatedRelease.GetName(), diffStr)
-	} else {
-		candidateRelease, err := m.getCandidateRelease(tiller, releaseName, chart, config)
+	}
+
+	resolver := NewChartResolver("")
+	if w.AuthSecret != "" {
+		auth, err := repoAuthFromSecret(clientset, w.AuthSecret)
 		if err != nil {
-			return nil, needsUpdate, fmt.Errorf("failed to generate candidate release: %s", err)
+			return nil, fmt.Errorf("failed to load chart repo auth secret %s: %s", w.AuthSecret, err)
 		}
+		resolver.Auth = auth
+	}
 
-		latestManifest := latestRelease.GetManifest()
-		if latestManifest == candidateRelease.GetManifest() {
-			err = m.reconcileRelease(m.namespace, latestManifest)
-			if err != nil {
-				return nil, needsUpdate, fmt.Errorf("reconcile error: %s", err)
-			}
-			updatedRelease = latestRelease
-			logrus.Infof("Reconciled release for %s release=%s", util.ResourceString(m.resource), updatedRelease.GetName())
-		} else {
-			updatedRelease, err = m.updateRelease(tiller, releaseName, chart, config)
-			if err != nil {
-				return nil, needsUpdate, fmt.Errorf("update error: %s", err)
-			}
-			needsUpdate = true
-			diffStr := util.Diff(latestManifest, updatedRelease.GetManifest())
-			logrus.Infof("Updated release for %s release=%s; diff:\n%s", util.ResourceString(m.resource), updatedRelease.GetName(), diffStr)
-		}
+	f := &managerFactory{
+		mgr:                  mgr,
+		getter:               getter,
+		storage:              newStorageFactory(clientset),
+		watch:                w,
+		resolver:             resolver,
+		log:                  logf.Log.WithName("helm.release").WithValues("kind", w.Kind),
+		pollInterval:         pollInterval,
+		reconcileTimeout:     reconcileTimeout,
+		hasStatusSubresource: w.HasStatusSubresource,
+	}
+	for _, opt := range opts {
+		opt(f)
 	}
-	return updatedRelease, needsUpdate, nil
+	return f, nil
 }
 
-// UninstallRelease uninstalls the managed release.
-func (m manager) UninstallRelease() (*rpb.Release, error) {
-	releaseName := m.releaseName
-
-	// Get history of this release
-	h, err := m.storageBackend.History(releaseName)
+// repoAuthFromSecret reads a Secret, in the operator's own namespace,
+// holding "username" and "password" keys and returns them as a RepoAuth.
+func repoAuthFromSecret(clientset kubernetes.Interface, name string) (*RepoAuth, error) {
+	ns, err := operatorNamespace()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get release history: %s", err)
-	}
-
-	// If there is no history, the release has already been uninstalled,
-	// so there's nothing to do.
-	if len(h) == 0 {
-		return nil, nil
+		return nil, err
 	}
-
-	tiller := m.tiller
-	uninstallResponse, err := tiller.UninstallRelease(context.TODO(), &services.UninstallReleaseRequest{
-		Name:  releaseName,
-		Purge: true,
-	})
+	secret, err := clientset.CoreV1().Secrets(ns).Get(context.TODO(), name, metav1.GetOptions{})
 	if err != nil {
 		return nil, err
 	}
-	uninstalledRelease := uninstallResponse.GetRelease()
-	diffStr := util.Diff(uninstalledRelease.GetManifest(), "")
-	logrus.Infof("Uninstalled release for %s release=%s; diff:\n%s", util.ResourceString(m.resource), releaseName, diffStr)
-	return uninstalledRelease, nil
+	return &RepoAuth{
+		Username: string(secret.Data["username"]),
+		Password: string(secret.Data["password"]),
+	}, nil
 }
 
-func (m manager) installRelease(tiller *tiller.ReleaseServer, namespace, name string, chart *cpb.Chart, config *cpb.Config) (*release.Release, error) {
-	installReq := &services.InstallReleaseRequest{
-		Namespace: namespace,
-		Name:      name,
-		Chart:     chart,
-		Values:    config,
+// operatorNamespace returns the namespace the operator's own pod is
+// running in, read from the Service Account token's namespace file that
+// the kubelet projects into every pod, or from the conventional
+// POD_NAMESPACE downward-API env var if the operator sets one instead.
+func operatorNamespace() (string, error) {
+	if ns := os.Getenv("POD_NAMESPACE"); ns != "" {
+		return ns, nil
 	}
-
-	releaseResponse, err := tiller.InstallRelease(context.TODO(), installReq)
+	data, err := ioutil.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
 	if err != nil {
-		// Workaround for helm/helm#3338
-		if releaseResponse.GetRelease() != nil {
-			uninstallReq := &services.UninstallReleaseRequest{
-				Name:  releaseResponse.GetRelease().GetName(),
-				Purge: true,
-			}
-			_, uninstallErr := tiller.UninstallRelease(context.TODO(), uninstallReq)
-			if uninstallErr != nil {
-				return nil, fmt.Errorf("failed to roll back failed installation: %s: %s", uninstallErr, err)
-			}
-		}
-		return nil, err
+		return "", fmt.Errorf("failed to determine operator namespace: %s", err)
 	}
-	return releaseResponse.GetRelease(), nil
+	return string(data), nil
 }
 
-func (m manager) updateRelease(tiller *tiller.ReleaseServer, name string, chart *cpb.Chart, config *cpb.Config) (*release.Release, error) {
-	updateReq := &services.UpdateReleaseRequest{
-		Name:   name,
-		Chart:  chart,
-		Values: config,
+func (f *managerFactory) NewManager(r *unstructured.Unstructured) (Manager, error) {
+	if r.GetNamespace() == "" {
+		return nil, fmt.Errorf("cannot manage a release for cluster-scoped resource %s: release storage requires a namespace", util.ResourceString(r))
 	}
 
-	releaseResponse, err := tiller.UpdateRelease(context.TODO(), updateReq)
-	if err != nil {
-		// Workaround for helm/helm#3338
-		if releaseResponse.GetRelease() != nil {
-			rollbackReq := &services.RollbackReleaseRequest{
-				Name:  name,
-				Force: true,
-			}
-			_, rollbackErr := tiller.RollbackRelease(context.TODO(), rollbackReq)
-			if rollbackErr != nil {
-				return nil, fmt.Errorf("failed to roll back failed update: %s: %s", rollbackErr, err)
-			}
-		}
-		return nil, err
-	}
-	return releaseResponse.GetRelease(), nil
+	releaseName := getReleaseName(r)
+	return &manager{
+		actionConfig:       f.actionConfigForCR(r),
+		resolveChart:       func() (string, error) { return f.resolver.Resolve(f.watch) },
+		client:             f.mgr.GetClient(),
+		log:                f.log.WithValues("namespace", r.GetNamespace(), "name", r.GetName(), "apiVersion", r.GetAPIVersion(), "release", releaseName),
+		extraPostRenderers: f.extraPostRenderers,
+
+		releaseName: releaseName,
+		namespace:   r.GetNamespace(),
+
+		resource: r,
+		spec:     r.Object["spec"],
+		status:   types.StatusFor(r),
+	}, nil
 }
 
-func (m manager) reconcileRelease(namespace string, expectedManifest string) error {
-	expectedInfos, err := m.tillerKubeClient.BuildUnstructured(namespace, bytes.NewBufferString(expectedManifest))
-	if err != nil {
-		return err
-	}
-	return expectedInfos.Visit(func(expected *resource.Info, err error) error {
-		if err != nil {
-			return err
-		}
-		helper := resource.NewHelper(expected.Client, expected.Mapping)
-		_, err = helper.Create(expected.Namespace, true, expected.Object)
-		if err == nil {
-			return nil
-		}
-		if !apierrors.IsAlreadyExists(err) {
-			return fmt.Errorf("create error: %s", err)
-		}
+func (f *managerFactory) ResyncPeriod() (time.Duration, bool) {
+	return f.pollInterval, f.pollInterval > 0
+}
 
-		patch, err := json.Marshal(expected.Object)
-		if err != nil {
-			return fmt.Errorf("failed to marshal JSON patch: %s", err)
-		}
+func (f *managerFactory) ReconcileTimeout() (time.Duration, bool) {
+	return f.reconcileTimeout, f.reconcileTimeout > 0
+}
 
-		_, err = helper.Patch(expected.Namespace, expected.Name, apitypes.MergePatchType, patch)
-		if err != nil {
-			return fmt.Errorf("patch error: %s", err)
-		}
-		return nil
-	})
+func (f *managerFactory) HasStatusSubresource() bool {
+	return f.hasStatusSubresource
 }
 
-func (m manager) getCandidateRelease(tiller *tiller.ReleaseServer, name string, chart *cpb.Chart, config *cpb.Config) (*release.Release, error) {
-	dryRunReq := &services.UpdateReleaseRequest{
-		Name:   name,
-		Chart:  chart,
-		Values: config,
-		DryRun: true,
+// actionConfigForCR builds an *action.Configuration scoped to the CR's
+// namespace. Its Releases store comes from f.storage, so every CR gets a
+// Storage bound to its own namespace instead of sharing one cluster-wide
+// store.
+func (f *managerFactory) actionConfigForCR(r *unstructured.Unstructured) *action.Configuration {
+	logFn := func(format string, v ...interface{}) {
+		f.log.V(1).Info(fmt.Sprintf(format, v...))
 	}
-	dryRunResponse, err := tiller.UpdateRelease(context.TODO(), dryRunReq)
-	if err != nil {
-		return nil, err
+	kubeClient := kube.New(f.getter)
+	kubeClient.Log = logFn
+	return &action.Configuration{
+		RESTClientGetter: f.getter,
+		Releases:         f.storage(r.GetNamespace()),
+		KubeClient:       kubeClient,
+		Log:              logFn,
 	}
-	return dryRunResponse.GetRelease(), nil
 }
 
-func (m manager) syncReleaseStatus(status types.HelmAppStatus) error {
-	if status.Release == nil {
-		return nil
-	}
+// Manager manages a Helm release. It can install, update, reconcile,
+// and uninstall a release.
+type Manager interface {
+	ReleaseName() string
+	IsInstalled() bool
+	IsUpdateRequired() bool
+	Sync(ctx context.Context) error
+	InstallRelease(ctx context.Context) (*rpb.Release, error)
+	UpdateRelease(ctx context.Context) (*rpb.Release, *rpb.Release, error)
+	UninstallRelease(ctx context.Context) (*rpb.Release, error)
+	ReconcileRelease(ctx context.Context) (*rpb.Release, error)
+	UninstallResourcesRemain(ctx context.Context, manifest string) (bool, error)
+
+	// ResourceReadiness inventories every object manifest renders and polls
+	// the live state of its workloads (Deployments, StatefulSets,
+	// DaemonSets, Jobs, Pods), returning that inventory alongside whether
+	// every workload in it is ready.
+	ResourceReadiness(ctx context.Context, manifest string) (map[string]types.ResourceRef, bool, error)
+}
 
-	name := status.Release.GetName()
-	version := status.Release.GetVersion()
-	_, err := m.storageBackend.Get(name, version)
-	if err == nil {
-		return nil
-	}
+type manager struct {
+	actionConfig       *action.Configuration
+	resolveChart       func() (string, error)
+	client             client.Client
+	log                logr.Logger
+	extraPostRenderers []postrender.PostRenderer
 
-	if !notFoundErr(err) {
-		return err
-	}
-	return m.storageBackend.Create(status.Release)
-}
+	releaseName string
+	namespace   string
 
-// tillerRendererForCR creates a ReleaseServer configured with a rendering engine that adds ownerrefs to rendered assets
-// based on the CR.
-func (f managerFactory) tillerRendererForCR(r *unstructured.Unstructured) *tiller.ReleaseServer {
-	controllerRef := metav1.NewControllerRef(r, r.GroupVersionKind())
-	ownerRefs := []metav1.OwnerReference{
-		*controllerRef,
-	}
-	baseEngine := helmengine.New()
-	e := engine.NewOwnerRefEngine(baseEngine, ownerRefs)
-	var ey environment.EngineYard = map[string]environment.Engine{
-		environment.GoTplEngine: e,
-	}
-	env := &environment.Environment{
-		EngineYard: ey,
-		Releases:   f.storageBackend,
-		KubeClient: f.tillerKubeClient,
-	}
-	kubeconfig, _ := f.tillerKubeClient.ToRESTConfig()
-	internalClientSet, _ := internalclientset.NewForConfig(kubeconfig)
+	resource *unstructured.Unstructured
+	spec     interface{}
+	status   *types.HelmAppStatus
+
+	chartPath string
+	chart     *chart.Chart
+	values    chartutil.Values
 
-	return tiller.NewReleaseServer(env, internalClientSet, false)
+	deployedRelease  *rpb.Release
+	candidateRelease *rpb.Release
+	isInstalled      bool
+	isUpdateRequired bool
 }
 
-func getReleaseName(r *unstructured.Unstructured) string {
-	return fmt.Sprintf("%s-%s", r.GetName(), shortenUID(r.GetUID()))
+func (m *manager) ReleaseName() string {
+	return m.releaseName
 }
 
-func notFoundErr(err error) bool {
-	return strings.Contains(err.Error(), "not found")
+func (m *manager) IsInstalled() bool {
+	return m.isInstalled
 }
 
-func valuesFromResource(r *unstructured.Unstructured) ([]byte, error) {
-	return yaml.Marshal(r.Object["spec"])
+func (m *manager) IsUpdateRequired() bool {
+	return m.isUpdateRequired
 }
 
-// processRequirements will process the requirements file
-// It will disable/enable the charts based on condition in requirements file
-// Also imports the specified chart values from child to parent.
-func processRequirements(chart *cpb.Chart, values *cpb.Config) error {
-	err := chartutil.ProcessRequirementsEnabled(chart, values)
+// Sync loads the chart and values for the managed CR, fetches the
+// currently deployed release (if any), and renders a dry-run candidate
+// release so IsInstalled/IsUpdateRequired reflect the release's actual
+// state before Install/Update/Reconcile are called.
+func (m *manager) Sync(ctx context.Context) error {
+	// resolveChart re-fetches the chart path on every Sync rather than
+	// caching it once, so that a watch referencing a floating version
+	// (a semver range against a repository, or a moving OCI tag) picks up
+	// a newly published chart on the operator's normal reconcile cadence.
+	chartPath, err := m.resolveChart()
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to resolve chart: %s", err)
+	}
+
+	// chart is mutated by the call to processRequirements, so we need to
+	// reload it every time regardless of whether resolveChart returned a
+	// cached path.
+	c, err := loader.Load(chartPath)
+	if err != nil {
+		return fmt.Errorf("failed to load chart: %s", err)
+	}
+	m.chartPath = chartPath
+	m.chart = c
+
+	inlineSpec, valuesFrom, err := splitSpecValuesFrom(m.spec)
+	if err != nil {
+		return fmt.Errorf("failed to parse values: %s", err)
+	}
+
+	cr, err := yaml.Marshal(inlineSpec)
+	if err != nil {
+		return fmt.Errorf("failed to parse values: %s", err)
 	}
-	err = chartutil.ProcessRequirementsImportValues(chart)
+	values, err := chartutil.ReadValues(cr)
 	if err != nil {
+		return fmt.Errorf("failed to parse values: %s", err)
+	}
+	m.values = values
+
+	// applySpecValuesFrom merges in the ConfigMap/Secret keys named in
+	// spec.valuesFrom, in list order, below the values already computed
+	// from the rest of spec, so a GitOps-managed CR can keep its values
+	// out of the CR body without losing to them on precedence.
+	if err := m.applySpecValuesFrom(ctx, valuesFrom); err != nil {
 		return err
 	}
+
+	// applyValuesOverrides merges in any ConfigMap/Secret/inline overrides
+	// named in the CR's annotations and validates the result against the
+	// chart's values.schema.json before the values are used for anything,
+	// so a bad override fails Sync instead of producing a broken release.
+	if err := m.applyValuesOverrides(ctx); err != nil {
+		return err
+	}
+
+	if err := processRequirements(m.chart, m.values); err != nil {
+		return fmt.Errorf("failed to process chart requirements: %s", err)
+	}
+
+	deployedRelease, err := m.getDeployedRelease()
+	if errors.Is(err, ErrNotFound) {
+		m.isInstalled = false
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get deployed release: %s", err)
+	}
+	m.deployedRelease = deployedRelease
+	m.isInstalled = true
+
+	candidateRelease, err := m.getCandidateRelease(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to render candidate release: %s", err)
+	}
+	m.candidateRelease = candidateRelease
+	// Change detection is a straight manifest diff scoped to this single
+	// Manager/release, never a process-wide resourceVersion comparison, so
+	// reconciling one CR can never cause another CR (or another GVK, when
+	// multiple watches share a process) to have its own changes skipped.
+	m.isUpdateRequired = deployedRelease.Manifest != candidateRelease.Manifest
+
 	return nil
 }
 
-func shortenUID(uid apitypes.UID) (shortUID string) {
-	u := uuid.Parse(string(uid))
-	uidBytes, err := u.MarshalBinary()
-	if err != nil {
-		shortUID = strings.Replace(string(uid), "-", "", -1)
+func (m *manager) getDeployedRelease() (*rpb.Release, error) {
+	get := action.NewGet(m.actionConfig)
+	return get.Run(m.releaseName)
+}
+
+func (m *manager) getCandidateRelease(ctx context.Context) (*rpb.Release, error) {
+	upgrade := action.NewUpgrade(m.actionConfig)
+	upgrade.Namespace = m.namespace
+	upgrade.DryRun = true
+	upgrade.PostRenderer = m.postRenderer()
+	return upgrade.RunWithContext(ctx, m.releaseName, m.chart, m.values)
+}
+
+// postRenderer builds the chain of post-render steps Helm runs the
+// rendered chart through: the built-in KustomizeEngine first (a no-op if
+// the chart has no kustomization.yaml), then any extraPostRenderers
+// configured on the factory via WithPostRenderers, and finally owner
+// reference injection, so a Kustomize overlay or custom decorator never
+// has the chance to strip the owner refs this operator relies on for
+// garbage collection.
+func (m *manager) postRenderer() postrender.PostRenderer {
+	controllerRef := metav1.NewControllerRef(m.resource, m.resource.GroupVersionKind())
+	ownerRefs := []metav1.OwnerReference{*controllerRef}
+
+	renderers := []postrender.PostRenderer{
+		engine.NewKustomizeEngine(m.chartPath, m.resource.GetAnnotations()[engine.KustomizeOverlayAnnotation]),
 	}
-	shortUID = strings.ToLower(base36.EncodeBytes(uidBytes))
-	return
+	renderers = append(renderers, m.extraPostRenderers...)
+	renderers = append(renderers, engine.NewOwnerRefPostRenderer(ownerRefs))
+	return engine.NewChain(renderers...)
 }
@@ -22,9 +22,7 @@ import (
 
 	yaml "gopkg.in/yaml.v2"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/helm/pkg/chartutil"
-	"k8s.io/helm/pkg/kube"
-	"k8s.io/helm/pkg/storage"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
 const (
@@ -44,11 +42,30 @@ const (
 	// value is typically singular and should be CamelCased (e.g. "MyApp").
 	KindEnvVar = "KIND"
 
-	// HelmChartEnvVar is the environment variable for the directory location
-	// of the helm chart to be installed for CRs that match the values for the
-	// API_VERSION and KIND environment variables.
+	// HelmChartEnvVar is the environment variable for the chart to be
+	// installed for CRs that match the values for the API_VERSION and KIND
+	// environment variables. It may be a local chart directory (the
+	// original behavior), a path or http(s):// URL to a packaged .tgz
+	// archive, or an oci:// reference; leave it empty and set
+	// HelmChartRepoEnvVar/HelmChartNameEnvVar instead to resolve the chart
+	// from a Helm chart repository index.
 	HelmChartEnvVar = "HELM_CHART"
 
+	// HelmChartRepoEnvVar, HelmChartNameEnvVar, and HelmChartVersionEnvVar
+	// are the environment variables for resolving a chart from a Helm
+	// chart repository index, as an alternative to HelmChartEnvVar. All
+	// three mirror the repo/name/version watch fields used by a
+	// HelmChartWatchesEnvVar config file.
+	HelmChartRepoEnvVar    = "HELM_CHART_REPO"
+	HelmChartNameEnvVar    = "HELM_CHART_NAME"
+	HelmChartVersionEnvVar = "HELM_CHART_VERSION"
+
+	// HasStatusSubresourceEnvVar is the environment variable indicating
+	// whether the watched CRD declares the status subresource, mirroring
+	// a single HelmChartWatchesEnvVar entry's hasStatusSubresource field.
+	// It has no effect when HelmChartWatchesEnvVar is used instead.
+	HasStatusSubresourceEnvVar = "HAS_STATUS_SUBRESOURCE"
+
 	defaultHelmChartWatchesFile = "/opt/helm/watches.yaml"
 )
 
@@ -56,20 +73,67 @@ type watch struct {
 	Group   string `yaml:"group"`
 	Version string `yaml:"version"`
 	Kind    string `yaml:"kind"`
-	Chart   string `yaml:"chart"`
-}
 
-// NewManager returns a new Helm manager capable of installing and uninstalling releases.
-func NewManager(storageBackend *storage.Storage, tillerKubeClient *kube.Client, chartDir string) Manager {
-	return manager{storageBackend, tillerKubeClient, chartDir}
+	// Chart identifies the chart to install for matching CRs. It may be a
+	// local chart directory (the original behavior), a path to a packaged
+	// .tgz archive, an http(s):// URL to a packaged archive, or an oci://
+	// reference. Repo-based watches leave Chart empty and set Repo/Name
+	// instead.
+	Chart string `yaml:"chart"`
+
+	// Repo, Name, and ChartVersion resolve a chart from a Helm chart
+	// repository index instead of Chart. ChartVersion may be a specific
+	// version or a semver constraint (e.g. "~1.2"), in which case
+	// PollInterval should typically be set so newly published versions
+	// are picked up.
+	Repo         string `yaml:"repo,omitempty"`
+	Name         string `yaml:"name,omitempty"`
+	ChartVersion string `yaml:"version,omitempty"`
+
+	// SHA256 and Provenance optionally verify a remote chart archive
+	// (downloaded via Chart, or resolved via Repo/Name/ChartVersion)
+	// before it is used. SHA256 is the hex-encoded digest of the packaged
+	// chart archive; Provenance is the URL of its detached .prov file.
+	SHA256     string `yaml:"sha256,omitempty"`
+	Provenance string `yaml:"provenance,omitempty"`
+
+	// PollInterval overrides the operator's default resync period for
+	// this watch alone, expressed as a Go duration (e.g. "5m"). It exists
+	// so watches that track a floating chart version can be re-resolved
+	// more often than watches that don't.
+	PollInterval string `yaml:"pollInterval,omitempty"`
+
+	// ReconcileTimeout bounds how long a single reconcile of this watch's
+	// CRs may run, expressed as a Go duration (e.g. "2m"). It exists so a
+	// chart with a slow or hanging hook can't block the worker processing
+	// this GVK indefinitely. When unset, the factory's ReconcileTimeout
+	// reports no value and the operator binary applies its default of 5m.
+	ReconcileTimeout string `yaml:"reconcileTimeout,omitempty"`
+
+	// AuthSecret names a Secret, in the operator's own namespace, holding
+	// "username" and "password" keys used for HTTP basic auth against a
+	// private chart repository index and archive download (Repo, or an
+	// http(s):// Chart URL). It has no effect on oci:// charts, which are
+	// authenticated through the ambient Docker credential store instead.
+	AuthSecret string `yaml:"authSecret,omitempty"`
+
+	// HasStatusSubresource should be true when this watch's CRD declares
+	// the status subresource (the standard, recommended configuration),
+	// so the reconciler writes status through the status subresource
+	// endpoint instead of a plain Update, which the API server would
+	// otherwise reject or silently no-op once the subresource is enabled.
+	HasStatusSubresource bool `yaml:"hasStatusSubresource,omitempty"`
 }
 
-// newManagerFromEnv returns a GVK and manager based on configuration provided
-// in the environment.
-func newManagerFromEnv(storageBackend *storage.Storage, tillerKubeClient *kube.Client) (schema.GroupVersionKind, Manager, error) {
+// newManagerFactoryFromEnv returns a GVK and ManagerFactory based on
+// configuration provided in the environment. The chart is identified
+// either by HelmChartEnvVar (a local directory, packaged archive, or
+// oci:// reference) or by HelmChartRepoEnvVar/HelmChartNameEnvVar (a Helm
+// chart repository lookup), mirroring the Chart and Repo/Name/Version
+// fields of a single HelmChartWatchesEnvVar entry.
+func newManagerFactoryFromEnv(mgr manager.Manager) (schema.GroupVersionKind, ManagerFactory, error) {
 	apiVersion := os.Getenv(APIVersionEnvVar)
 	kind := os.Getenv(KindEnvVar)
-	chartDir := os.Getenv(HelmChartEnvVar)
 
 	var gvk schema.GroupVersionKind
 	gv, err := schema.ParseGroupVersion(apiVersion)
@@ -82,30 +146,47 @@ func newManagerFromEnv(storageBackend *storage.Storage, tillerKubeClient *kube.C
 		return gvk, nil, fmt.Errorf("invalid GVK: %s: %s", gvk, err)
 	}
 
-	if _, err := chartutil.IsChartDir(chartDir); err != nil {
-		return gvk, nil, fmt.Errorf("invalid chart directory %s: %s", chartDir, err)
+	w := watch{
+		Group:                gvk.Group,
+		Version:              gvk.Version,
+		Kind:                 gvk.Kind,
+		Chart:                os.Getenv(HelmChartEnvVar),
+		Repo:                 os.Getenv(HelmChartRepoEnvVar),
+		Name:                 os.Getenv(HelmChartNameEnvVar),
+		ChartVersion:         os.Getenv(HelmChartVersionEnvVar),
+		HasStatusSubresource: isTruthy(os.Getenv(HasStatusSubresourceEnvVar)),
 	}
 
-	manager := NewManager(storageBackend, tillerKubeClient, chartDir)
-	return gvk, manager, nil
+	// Resolve the chart once up front so a misconfigured HELM_CHART* env
+	// var fails fast at startup instead of on the first reconcile, the
+	// same as NewManagerFactoriesFromFile does for each watches.yaml entry.
+	if _, err := NewChartResolver("").Resolve(w); err != nil {
+		return gvk, nil, fmt.Errorf("failed to resolve chart: %s", err)
+	}
+
+	factory, err := newManagerFactory(mgr, w)
+	if err != nil {
+		return gvk, nil, err
+	}
+	return gvk, factory, nil
 }
 
-// NewManagersFromEnv returns a map of managers, keyed by GVK, based on
-// configuration provided in the environment.
-func NewManagersFromEnv(storageBackend *storage.Storage, tillerKubeClient *kube.Client) (map[schema.GroupVersionKind]Manager, error) {
+// NewManagerFactoriesFromEnv returns a map of ManagerFactories, keyed by
+// GVK, based on configuration provided in the environment.
+func NewManagerFactoriesFromEnv(mgr manager.Manager) (map[schema.GroupVersionKind]ManagerFactory, error) {
 	if watchesFile, ok := getWatchesFile(); ok {
-		return NewManagersFromFile(storageBackend, tillerKubeClient, watchesFile)
+		return NewManagerFactoriesFromFile(mgr, watchesFile)
 	}
-	gvk, manager, err := newManagerFromEnv(storageBackend, tillerKubeClient)
+	gvk, factory, err := newManagerFactoryFromEnv(mgr)
 	if err != nil {
 		return nil, err
 	}
-	return map[schema.GroupVersionKind]Manager{gvk: manager}, nil
+	return map[schema.GroupVersionKind]ManagerFactory{gvk: factory}, nil
 }
 
-// NewManagersFromFile reads the config file at the provided path and returns a map
-// of managers, keyed by each GVK in the config.
-func NewManagersFromFile(storageBackend *storage.Storage, tillerKubeClient *kube.Client, path string) (map[schema.GroupVersionKind]Manager, error) {
+// NewManagerFactoriesFromFile reads the config file at the provided path
+// and returns a map of ManagerFactories, keyed by each GVK in the config.
+func NewManagerFactoriesFromFile(mgr manager.Manager, path string) (map[schema.GroupVersionKind]ManagerFactory, error) {
 	b, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %s", err)
@@ -116,7 +197,8 @@ func NewManagersFromFile(storageBackend *storage.Storage, tillerKubeClient *kube
 		return nil, fmt.Errorf("failed to unmarshal config: %s", err)
 	}
 
-	m := map[schema.GroupVersionKind]Manager{}
+	resolver := NewChartResolver("")
+	m := map[schema.GroupVersionKind]ManagerFactory{}
 	for _, w := range watches {
 		gvk := schema.GroupVersionKind{
 			Group:   w.Group,
@@ -128,14 +210,24 @@ func NewManagersFromFile(storageBackend *storage.Storage, tillerKubeClient *kube
 			return nil, fmt.Errorf("invalid GVK: %s: %s", gvk, err)
 		}
 
-		if _, err := chartutil.IsChartDir(w.Chart); err != nil {
-			return nil, fmt.Errorf("invalid chart directory %s: %s", w.Chart, err)
-		}
-
 		if _, ok := m[gvk]; ok {
 			return nil, fmt.Errorf("duplicate GVK: %s", gvk)
 		}
-		m[gvk] = NewManager(storageBackend, tillerKubeClient, w.Chart)
+		factory, err := newManagerFactory(mgr, w)
+		if err != nil {
+			return nil, err
+		}
+
+		// Resolve the chart once up front so a misconfigured watch fails
+		// fast at startup instead of on the first reconcile. The watch's
+		// AuthSecret credentials, loaded by the factory, apply here too.
+		// The factory re-resolves on every Sync so that floating chart
+		// versions are re-checked on each reconcile.
+		resolver.Auth = factory.(*managerFactory).resolver.Auth
+		if _, err := resolver.Resolve(w); err != nil {
+			return nil, fmt.Errorf("failed to resolve chart for %s: %s", gvk, err)
+		}
+		m[gvk] = factory
 	}
 	return m, nil
 }
@@ -0,0 +1,157 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/operator-framework/helm-app-operator-kit/helm-app-operator/pkg/helm/internal/types"
+	"github.com/operator-framework/helm-app-operator-kit/helm-app-operator/pkg/helm/internal/util"
+)
+
+// workloadKinds are the kinds ResourceReadiness polls for a readiness
+// state; any other kind (Role, RoleBinding, ClusterRole,
+// ClusterRoleBinding, PodSecurityPolicy, ConfigMap, Secret, Service, ...)
+// has no meaningful ready state of its own and is always reported ready.
+var workloadKinds = map[string]bool{
+	"Deployment":  true,
+	"StatefulSet": true,
+	"DaemonSet":   true,
+	"Job":         true,
+	"Pod":         true,
+}
+
+// ResourceReadiness builds the release's resource inventory from manifest,
+// polling the live state of every workload it contains (Deployments,
+// StatefulSets, DaemonSets, Jobs, Pods), and rolls those up into a single
+// ready bool: true only once every workload reports its expected replica
+// count.
+func (m *manager) ResourceReadiness(ctx context.Context, manifest string) (map[string]types.ResourceRef, bool, error) {
+	objs, err := util.ManifestObjects(manifest)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse manifest: %s", err)
+	}
+
+	resources := make(map[string]types.ResourceRef, len(objs))
+	ready := true
+	for _, obj := range objs {
+		ref := types.ResourceRef{
+			APIVersion: obj.GetAPIVersion(),
+			Kind:       obj.GetKind(),
+			Namespace:  obj.GetNamespace(),
+			Name:       obj.GetName(),
+			Status:     "Ready",
+		}
+		if workloadKinds[obj.GetKind()] {
+			status, workloadReady, err := m.workloadStatus(ctx, obj)
+			if err != nil {
+				return nil, false, err
+			}
+			ref.Status = status
+			if !workloadReady {
+				ready = false
+			}
+		}
+		resources[resourceKey(obj)] = ref
+	}
+	return resources, ready, nil
+}
+
+// resourceKey identifies obj within a resource inventory.
+func resourceKey(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return fmt.Sprintf("%s/%s", obj.GetKind(), obj.GetName())
+	}
+	return fmt.Sprintf("%s/%s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+}
+
+// workloadStatus polls the live state of a workload obj describes,
+// returning a human-readable status and whether it's ready.
+func (m *manager) workloadStatus(ctx context.Context, obj *unstructured.Unstructured) (string, bool, error) {
+	key := client.ObjectKey{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+
+	switch obj.GetKind() {
+	case "Deployment":
+		d := &appsv1.Deployment{}
+		if found, err := m.getWorkload(ctx, key, d); !found || err != nil {
+			return "NotFound", false, err
+		}
+		desired := int32(1)
+		if d.Spec.Replicas != nil {
+			desired = *d.Spec.Replicas
+		}
+		ready := d.Status.ReadyReplicas >= desired
+		return fmt.Sprintf("%d/%d ready", d.Status.ReadyReplicas, desired), ready, nil
+
+	case "StatefulSet":
+		s := &appsv1.StatefulSet{}
+		if found, err := m.getWorkload(ctx, key, s); !found || err != nil {
+			return "NotFound", false, err
+		}
+		desired := int32(1)
+		if s.Spec.Replicas != nil {
+			desired = *s.Spec.Replicas
+		}
+		ready := s.Status.ReadyReplicas >= desired
+		return fmt.Sprintf("%d/%d ready", s.Status.ReadyReplicas, desired), ready, nil
+
+	case "DaemonSet":
+		ds := &appsv1.DaemonSet{}
+		if found, err := m.getWorkload(ctx, key, ds); !found || err != nil {
+			return "NotFound", false, err
+		}
+		ready := ds.Status.NumberReady >= ds.Status.DesiredNumberScheduled
+		return fmt.Sprintf("%d/%d ready", ds.Status.NumberReady, ds.Status.DesiredNumberScheduled), ready, nil
+
+	case "Job":
+		j := &batchv1.Job{}
+		if found, err := m.getWorkload(ctx, key, j); !found || err != nil {
+			return "NotFound", false, err
+		}
+		ready := j.Status.Succeeded > 0 && (j.Spec.Completions == nil || j.Status.Succeeded >= *j.Spec.Completions)
+		return fmt.Sprintf("%d succeeded", j.Status.Succeeded), ready, nil
+
+	case "Pod":
+		p := &corev1.Pod{}
+		if found, err := m.getWorkload(ctx, key, p); !found || err != nil {
+			return "NotFound", false, err
+		}
+		ready := p.Status.Phase == corev1.PodRunning || p.Status.Phase == corev1.PodSucceeded
+		return string(p.Status.Phase), ready, nil
+	}
+
+	return "Ready", true, nil
+}
+
+// getWorkload fetches obj into out, reporting found as false rather than
+// erroring when the object doesn't exist on the cluster yet.
+func (m *manager) getWorkload(ctx context.Context, key client.ObjectKey, out client.Object) (bool, error) {
+	err := m.client.Get(ctx, key, out)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get %s %s: %s", key.Namespace, key.Name, err)
+	}
+	return true, nil
+}
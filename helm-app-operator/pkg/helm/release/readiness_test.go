@@ -0,0 +1,161 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestResourceKey(t *testing.T) {
+	cases := []struct {
+		name string
+		obj  *unstructured.Unstructured
+		want string
+	}{
+		{
+			name: "namespaced",
+			obj:  newUnstructured("apps/v1", "Deployment", "my-ns", "my-app"),
+			want: "Deployment/my-ns/my-app",
+		},
+		{
+			name: "cluster-scoped",
+			obj:  newUnstructured("rbac.authorization.k8s.io/v1", "ClusterRole", "", "my-role"),
+			want: "ClusterRole/my-role",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := resourceKey(c.obj); got != c.want {
+				t.Errorf("resourceKey() = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestResourceReadinessSkipsStatusLessKinds(t *testing.T) {
+	manifest := `
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: my-role
+  namespace: my-ns
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: my-config
+  namespace: my-ns
+`
+	m := &manager{client: fake.NewFakeClientWithScheme(scheme.Scheme)}
+
+	resources, ready, err := m.ResourceReadiness(context.TODO(), manifest)
+	if err != nil {
+		t.Fatalf("ResourceReadiness returned error: %s", err)
+	}
+	if !ready {
+		t.Error("ready = false, want true: manifest has no workload kinds to wait on")
+	}
+	role, ok := resources["Role/my-ns/my-role"]
+	if !ok {
+		t.Fatal("resources missing Role/my-ns/my-role")
+	}
+	if role.Status != "Ready" {
+		t.Errorf("Role status = %q, want %q", role.Status, "Ready")
+	}
+}
+
+func TestResourceReadinessDeployment(t *testing.T) {
+	manifest := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: my-app
+  namespace: my-ns
+`
+	cases := []struct {
+		name       string
+		deployment *appsv1.Deployment
+		wantReady  bool
+	}{
+		{
+			name: "ready replicas match desired",
+			deployment: &appsv1.Deployment{
+				ObjectMeta: metaObj("my-app", "my-ns"),
+				Spec:       appsv1.DeploymentSpec{Replicas: replicas(3)},
+				Status:     appsv1.DeploymentStatus{ReadyReplicas: 3},
+			},
+			wantReady: true,
+		},
+		{
+			name: "ready replicas below desired",
+			deployment: &appsv1.Deployment{
+				ObjectMeta: metaObj("my-app", "my-ns"),
+				Spec:       appsv1.DeploymentSpec{Replicas: replicas(3)},
+				Status:     appsv1.DeploymentStatus{ReadyReplicas: 1},
+			},
+			wantReady: false,
+		},
+		{
+			name:      "not found on the cluster yet",
+			wantReady: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			objs := []runtime.Object{}
+			if c.deployment != nil {
+				objs = append(objs, c.deployment)
+			}
+			m := &manager{client: fake.NewFakeClientWithScheme(scheme.Scheme, objs...)}
+
+			resources, ready, err := m.ResourceReadiness(context.TODO(), manifest)
+			if err != nil {
+				t.Fatalf("ResourceReadiness returned error: %s", err)
+			}
+			if ready != c.wantReady {
+				t.Errorf("ready = %v, want %v", ready, c.wantReady)
+			}
+			if _, ok := resources["Deployment/my-ns/my-app"]; !ok {
+				t.Fatal("resources missing Deployment/my-ns/my-app")
+			}
+		})
+	}
+}
+
+func newUnstructured(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
+
+func metaObj(name, namespace string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: name, Namespace: namespace}
+}
+
+func replicas(n int32) *int32 {
+	return &n
+}
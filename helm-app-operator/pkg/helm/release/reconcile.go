@@ -0,0 +1,93 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	rpb "helm.sh/helm/v3/pkg/release"
+
+	"github.com/operator-framework/helm-app-operator-kit/helm-app-operator/pkg/helm/internal/util"
+)
+
+// ReconcileRelease ensures the resources described by the currently
+// deployed release's manifest exist and match the cluster, without
+// installing or upgrading the release itself.
+func (m *manager) ReconcileRelease(ctx context.Context) (*rpb.Release, error) {
+	if m.deployedRelease == nil {
+		return nil, fmt.Errorf("no deployed release to reconcile")
+	}
+	if err := m.reconcileManifest(ctx, m.deployedRelease.Manifest); err != nil {
+		return nil, err
+	}
+	return m.deployedRelease, nil
+}
+
+// reconcileManifest ensures that every resource described by manifest
+// exists in the cluster, creating anything that's missing and bringing
+// anything that's drifted back in line with the last deployed release.
+//
+// Drift is computed as a three-way merge between the previously deployed
+// manifest (the "original"), the freshly rendered manifest (the
+// "modified"/target), and whatever is actually live on the server. Helm's
+// own kube.Client.Update already performs exactly this three-way patch
+// internally: strategicpatch.CreateThreeWayMergePatch, applied as a
+// StrategicMergePatchType, for built-in kinds with a registered patch
+// schema, and jsonmergepatch.CreateThreeWayJSONMergePatch, applied as a
+// MergePatchType, for CRDs/unstructured kinds; it also already no-ops a
+// `{}` patch and preserves resourceVersion/status from the live object.
+// reconcileManifest's job is only to hand it the correct "original"
+// (instead of diffing the target against itself, which would blindly
+// overwrite fields other controllers manage, e.g. HPA replicas or Service
+// clusterIP).
+func (m *manager) reconcileManifest(ctx context.Context, manifest string) error {
+	kubeClient := m.actionConfig.KubeClient
+
+	target, err := kubeClient.Build(strings.NewReader(manifest), false)
+	if err != nil {
+		return fmt.Errorf("failed to build target resources: %s", err)
+	}
+	m.log.V(1).Info(fmt.Sprintf("building %d resource(s)", len(target)))
+
+	original := target
+	lastManifest := ""
+	if m.deployedRelease != nil {
+		lastManifest = m.deployedRelease.Manifest
+	}
+	if lastManifest != "" && lastManifest != manifest {
+		if o, err := kubeClient.Build(strings.NewReader(lastManifest), false); err == nil {
+			original = o
+		}
+	}
+
+	// An empty manifest diff says nothing about the live objects, which may
+	// have been deleted or edited on the cluster, so it doesn't skip the
+	// apply below; kubeClient.Update itself skips objects whose patch is
+	// empty.
+	if diffStr := util.Diff(lastManifest, manifest); strings.TrimSpace(diffStr) != "" {
+		m.log.V(1).Info("Reconciling", "diff", diffStr)
+	}
+
+	if _, err := kubeClient.Create(target); err != nil {
+		// Resources that already exist are expected; anything the cluster
+		// already has is reconciled below via a three-way patch instead.
+		if _, updateErr := kubeClient.Update(original, target, false); updateErr != nil {
+			return fmt.Errorf("failed to create or update resources: %s: %s", err, updateErr)
+		}
+	}
+	return nil
+}
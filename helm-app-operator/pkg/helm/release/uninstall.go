@@ -0,0 +1,66 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"helm.sh/helm/v3/pkg/action"
+	rpb "helm.sh/helm/v3/pkg/release"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/operator-framework/helm-app-operator-kit/helm-app-operator/pkg/helm/internal/util"
+)
+
+// UninstallRelease removes the managed release, returning ErrNotFound if
+// no release history exists.
+func (m *manager) UninstallRelease(ctx context.Context) (*rpb.Release, error) {
+	uninstall := action.NewUninstall(m.actionConfig)
+	res, err := uninstall.Run(m.releaseName)
+	if err != nil {
+		return nil, err
+	}
+	diffStr := util.Diff(res.Release.Manifest, "")
+	m.log.V(1).Info("Uninstalled release", "diff", diffStr)
+	return res.Release, nil
+}
+
+// UninstallResourcesRemain reports whether any resource described by
+// manifest, the Manifest of a just-uninstalled release, still exists on
+// the API server. It's used to implement the UninstallWaitAnnotation,
+// where the uninstall-helm-release finalizer must not be released until
+// the cluster has actually finished deleting everything Helm removed.
+func (m *manager) UninstallResourcesRemain(ctx context.Context, manifest string) (bool, error) {
+	objs, err := util.ManifestObjects(manifest)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse uninstalled manifest: %s", err)
+	}
+	m.log.V(1).Info("Checking for remaining release resources", "count", len(objs))
+	for _, obj := range objs {
+		key := client.ObjectKey{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+		err := m.client.Get(ctx, key, obj)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to get %s: %s", util.ResourceString(obj), err)
+		}
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestUninstallResourcesRemain(t *testing.T) {
+	manifest := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: my-config
+  namespace: my-ns
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: my-secret
+  namespace: my-ns
+`
+	cases := []struct {
+		name    string
+		objs    []runtime.Object
+		want    bool
+		wantErr bool
+	}{
+		{
+			name: "all resources already deleted",
+			objs: nil,
+			want: false,
+		},
+		{
+			name: "one resource still remains (partial deletion)",
+			objs: []runtime.Object{
+				&corev1.ConfigMap{ObjectMeta: metaObj("my-config", "my-ns")},
+			},
+			want: true,
+		},
+		{
+			name: "every resource still remains",
+			objs: []runtime.Object{
+				&corev1.ConfigMap{ObjectMeta: metaObj("my-config", "my-ns")},
+				&corev1.Secret{ObjectMeta: metaObj("my-secret", "my-ns")},
+			},
+			want: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := &manager{client: fake.NewFakeClientWithScheme(scheme.Scheme, c.objs...)}
+
+			remain, err := m.UninstallResourcesRemain(context.TODO(), manifest)
+			if c.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UninstallResourcesRemain returned error: %s", err)
+			}
+			if remain != c.want {
+				t.Errorf("remain = %v, want %v", remain, c.want)
+			}
+		})
+	}
+}
+
+func TestUninstallResourcesRemainEmptyManifest(t *testing.T) {
+	m := &manager{client: fake.NewFakeClientWithScheme(scheme.Scheme)}
+
+	remain, err := m.UninstallResourcesRemain(context.TODO(), "")
+	if err != nil {
+		t.Fatalf("UninstallResourcesRemain returned error: %s", err)
+	}
+	if remain {
+		t.Error("remain = true, want false: an empty manifest describes no resources to wait on")
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"context"
+	"fmt"
+
+	"helm.sh/helm/v3/pkg/action"
+	rpb "helm.sh/helm/v3/pkg/release"
+)
+
+// UpdateRelease performs a Helm v3 upgrade of the managed release using the
+// values computed during Sync, returning both the previously deployed
+// release and the newly updated one so callers can log a diff.
+func (m *manager) UpdateRelease(ctx context.Context) (*rpb.Release, *rpb.Release, error) {
+	upgrade := action.NewUpgrade(m.actionConfig)
+	upgrade.Namespace = m.namespace
+	upgrade.PostRenderer = m.postRenderer()
+	upgrade.Force = isTruthy(m.resource.GetAnnotations()[upgradeForceAnnotation])
+
+	updatedRelease, err := upgrade.RunWithContext(ctx, m.releaseName, m.chart, m.values)
+	if err != nil {
+		if isTruthy(m.resource.GetAnnotations()[rollbackOnFailureAnnotation]) {
+			if rollbackErr := m.rollbackToDeployed(ctx); rollbackErr != nil {
+				return nil, nil, fmt.Errorf("upgrade failed and rollback failed: %s: %s", err, rollbackErr)
+			}
+			return nil, nil, fmt.Errorf("%w: %s", ErrRolledBack, err)
+		}
+		return nil, nil, err
+	}
+	return m.deployedRelease, updatedRelease, nil
+}
+
+// rollbackToDeployed rolls the managed release back to its last DEPLOYED
+// revision, looked up in release storage and passed to action.Rollback
+// explicitly (left at zero, Version would target the previous revision,
+// whatever its status).
+func (m *manager) rollbackToDeployed(ctx context.Context) error {
+	deployed, err := m.actionConfig.Releases.Deployed(m.releaseName)
+	if err != nil {
+		return fmt.Errorf("failed to find last deployed revision: %s", err)
+	}
+	rollback := action.NewRollback(m.actionConfig)
+	rollback.Version = deployed.Version
+	return rollback.Run(m.releaseName)
+}
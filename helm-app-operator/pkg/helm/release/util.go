@@ -0,0 +1,50 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/martinlindhe/base36"
+	"github.com/pborman/uuid"
+	apitypes "k8s.io/apimachinery/pkg/types"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func getReleaseName(r *unstructured.Unstructured) string {
+	return fmt.Sprintf("%s-%s", r.GetName(), shortenUID(r.GetUID()))
+}
+
+// processRequirements resolves a chart's declared dependencies against the
+// supplied values, enabling/disabling conditional subcharts and importing
+// values from child to parent as declared in Chart.yaml.
+func processRequirements(c *chart.Chart, values chartutil.Values) error {
+	return chartutil.ProcessDependencies(c, values)
+}
+
+func shortenUID(uid apitypes.UID) (shortUID string) {
+	u := uuid.Parse(string(uid))
+	uidBytes, err := u.MarshalBinary()
+	if err != nil {
+		shortUID = strings.Replace(string(uid), "-", "", -1)
+	}
+	shortUID = strings.ToLower(base36.EncodeBytes(uidBytes))
+	return
+}
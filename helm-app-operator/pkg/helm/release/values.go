@@ -0,0 +1,302 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apitypes "k8s.io/apimachinery/pkg/types"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/strvals"
+)
+
+// ErrValidationFailed is returned by Sync when the values computed for a
+// release fail validation against the chart's values.schema.json.
+var ErrValidationFailed = errors.New("values failed schema validation")
+
+// valuesFromRef is one entry of the helm.operator-sdk/values-from
+// annotation.
+type valuesFromRef struct {
+	ConfigMapRef *valuesFromSourceRef `yaml:"configMapRef,omitempty"`
+	SecretRef    *valuesFromSourceRef `yaml:"secretRef,omitempty"`
+}
+
+type valuesFromSourceRef struct {
+	Name     string `yaml:"name"`
+	Optional bool   `yaml:"optional,omitempty"`
+}
+
+// specValuesFromRef is one entry of a CR's spec.valuesFrom list. Unlike
+// valuesFromRef, which names a whole ConfigMap/Secret via an annotation,
+// each entry here selects a single key, in the same shape as a Pod's
+// env[].valueFrom, so a GitOps-managed CR can keep its values out of the
+// CR body while still pinning exactly which key backs them.
+type specValuesFromRef struct {
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+	SecretKeyRef    *corev1.SecretKeySelector    `json:"secretKeyRef,omitempty"`
+}
+
+// splitSpecValuesFrom pulls the valuesFrom list, if any, out of a CR's
+// spec and returns the remaining fields (the inline values, which take
+// precedence over every valuesFrom entry) alongside the parsed refs. A
+// spec that isn't a map, or has no valuesFrom key, is returned unchanged.
+func splitSpecValuesFrom(spec interface{}) (interface{}, []specValuesFromRef, error) {
+	specMap, ok := spec.(map[string]interface{})
+	if !ok {
+		return spec, nil, nil
+	}
+	raw, ok := specMap["valuesFrom"]
+	if !ok {
+		return spec, nil, nil
+	}
+
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse spec.valuesFrom: %s", err)
+	}
+	var refs []specValuesFromRef
+	if err := json.Unmarshal(b, &refs); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse spec.valuesFrom: %s", err)
+	}
+
+	inline := make(map[string]interface{}, len(specMap)-1)
+	for k, v := range specMap {
+		if k == "valuesFrom" {
+			continue
+		}
+		inline[k] = v
+	}
+	return inline, refs, nil
+}
+
+// applyValuesOverrides merges, in precedence order, the CR's spec values,
+// any ConfigMaps and Secrets named in the values-from annotation, and any
+// inline overrides in the set annotation, and then validates the result
+// against the chart's values.schema.json, if it has one.
+func (m *manager) applyValuesOverrides(ctx context.Context) error {
+	refs, err := parseValuesFrom(m.resource.GetAnnotations()[valuesFromAnnotation])
+	if err != nil {
+		return fmt.Errorf("failed to parse %s annotation: %s", valuesFromAnnotation, err)
+	}
+
+	var configMapValues, secretValues chartutil.Values
+	for _, ref := range refs {
+		switch {
+		case ref.ConfigMapRef != nil:
+			v, err := m.readValuesFromConfigMap(ctx, ref.ConfigMapRef)
+			if err != nil {
+				return err
+			}
+			configMapValues = chartutil.CoalesceTables(v, configMapValues)
+		case ref.SecretRef != nil:
+			v, err := m.readValuesFromSecret(ctx, ref.SecretRef)
+			if err != nil {
+				return err
+			}
+			secretValues = chartutil.CoalesceTables(v, secretValues)
+		}
+	}
+
+	setValues, err := parseSetValues(m.resource.GetAnnotations()[setAnnotation])
+	if err != nil {
+		return fmt.Errorf("failed to parse %s annotation: %s", setAnnotation, err)
+	}
+
+	// Precedence, lowest to highest: CR spec < ConfigMap < Secret <
+	// annotation-set. chartutil.CoalesceTables(dst, src) fills in dst with
+	// values from src wherever dst doesn't already define them, so the
+	// higher-precedence side must always be passed as dst.
+	values := chartutil.Values(m.values)
+	values = chartutil.CoalesceTables(configMapValues, values)
+	values = chartutil.CoalesceTables(secretValues, values)
+	values = chartutil.CoalesceTables(setValues, values)
+	m.values = values
+
+	if len(m.chart.Schema) > 0 {
+		if err := chartutil.ValidateAgainstSchema(m.chart, m.values); err != nil {
+			return fmt.Errorf("%w: %s", ErrValidationFailed, err)
+		}
+	}
+	return nil
+}
+
+// applySpecValuesFrom resolves refs, a CR's spec.valuesFrom entries,
+// against ConfigMap/Secret keys in the CR's namespace and deep-merges
+// them under m.values in list order: chart defaults (coalesced later by
+// Helm itself) < refs[0] < refs[1] < ... < m.values, which already holds
+// every inline field of spec and so outranks all of them.
+func (m *manager) applySpecValuesFrom(ctx context.Context, refs []specValuesFromRef) error {
+	var fromValues chartutil.Values
+	for _, ref := range refs {
+		var v chartutil.Values
+		var err error
+		switch {
+		case ref.ConfigMapKeyRef != nil:
+			v, err = m.readSpecValuesFromConfigMapKey(ctx, ref.ConfigMapKeyRef)
+		case ref.SecretKeyRef != nil:
+			v, err = m.readSpecValuesFromSecretKey(ctx, ref.SecretKeyRef)
+		default:
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// Each entry takes precedence over the ones before it, so the new
+		// value is passed as dst and the running total as src.
+		fromValues = chartutil.CoalesceTables(v, fromValues)
+	}
+	m.values = chartutil.CoalesceTables(m.values, fromValues)
+	return nil
+}
+
+func (m *manager) readSpecValuesFromConfigMapKey(ctx context.Context, ref *corev1.ConfigMapKeySelector) (chartutil.Values, error) {
+	cm := &corev1.ConfigMap{}
+	key := apitypes.NamespacedName{Namespace: m.namespace, Name: ref.Name}
+	if err := m.client.Get(ctx, key, cm); err != nil {
+		if apierrors.IsNotFound(err) && ref.Optional != nil && *ref.Optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get ConfigMap %s: %s", key, err)
+	}
+	data, ok := cm.Data[ref.Key]
+	if !ok {
+		if ref.Optional != nil && *ref.Optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ConfigMap %s has no key %q", key, ref.Key)
+	}
+	return chartutil.ReadValues([]byte(data))
+}
+
+func (m *manager) readSpecValuesFromSecretKey(ctx context.Context, ref *corev1.SecretKeySelector) (chartutil.Values, error) {
+	secret := &corev1.Secret{}
+	key := apitypes.NamespacedName{Namespace: m.namespace, Name: ref.Name}
+	if err := m.client.Get(ctx, key, secret); err != nil {
+		if apierrors.IsNotFound(err) && ref.Optional != nil && *ref.Optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get Secret %s: %s", key, err)
+	}
+	data, ok := secret.Data[ref.Key]
+	if !ok {
+		if ref.Optional != nil && *ref.Optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Secret %s has no key %q", key, ref.Key)
+	}
+	return chartutil.ReadValues(data)
+}
+
+func (m *manager) readValuesFromConfigMap(ctx context.Context, ref *valuesFromSourceRef) (chartutil.Values, error) {
+	cm := &corev1.ConfigMap{}
+	key := apitypes.NamespacedName{Namespace: m.namespace, Name: ref.Name}
+	if err := m.client.Get(ctx, key, cm); err != nil {
+		if apierrors.IsNotFound(err) && ref.Optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get ConfigMap %s: %s", key, err)
+	}
+	return dataToValues(cm.Data)
+}
+
+func (m *manager) readValuesFromSecret(ctx context.Context, ref *valuesFromSourceRef) (chartutil.Values, error) {
+	secret := &corev1.Secret{}
+	key := apitypes.NamespacedName{Namespace: m.namespace, Name: ref.Name}
+	if err := m.client.Get(ctx, key, secret); err != nil {
+		if apierrors.IsNotFound(err) && ref.Optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get Secret %s: %s", key, err)
+	}
+	data := make(map[string]string, len(secret.Data))
+	for k, v := range secret.Data {
+		data[k] = string(v)
+	}
+	return dataToValues(data)
+}
+
+// dataToValues turns a ConfigMap/Secret data map into a values tree. The
+// conventional key "values.yaml" is parsed as a full values document and
+// merged at the top level; every other key is parsed as a single YAML
+// scalar or mapping and merged under a top-level key matching its name.
+func dataToValues(data map[string]string) (chartutil.Values, error) {
+	values := chartutil.Values{}
+	for k, v := range data {
+		if k == "values.yaml" {
+			parsed, err := chartutil.ReadValues([]byte(v))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse values.yaml: %s", err)
+			}
+			values = chartutil.CoalesceTables(values, parsed)
+			continue
+		}
+		var parsed interface{}
+		if err := yaml.Unmarshal([]byte(v), &parsed); err != nil {
+			return nil, fmt.Errorf("failed to parse key %q: %s", k, err)
+		}
+		values[k] = normalizeYAML(parsed)
+	}
+	return values, nil
+}
+
+// normalizeYAML converts the map[interface{}]interface{} values produced by
+// gopkg.in/yaml.v2 into map[string]interface{}, which is what chartutil and
+// the JSON-based schema validator expect.
+func normalizeYAML(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			m[fmt.Sprintf("%v", k)] = normalizeYAML(val)
+		}
+		return m
+	case []interface{}:
+		for i, e := range t {
+			t[i] = normalizeYAML(e)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+func parseValuesFrom(annotation string) ([]valuesFromRef, error) {
+	if annotation == "" {
+		return nil, nil
+	}
+	var refs []valuesFromRef
+	if err := yaml.Unmarshal([]byte(annotation), &refs); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+func parseSetValues(annotation string) (chartutil.Values, error) {
+	if annotation == "" {
+		return chartutil.Values{}, nil
+	}
+	values := map[string]interface{}{}
+	if err := strvals.ParseInto(annotation, values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
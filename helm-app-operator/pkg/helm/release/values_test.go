@@ -0,0 +1,285 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestParseValuesFrom(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation string
+		want       []valuesFromRef
+		wantErr    bool
+	}{
+		{
+			name:       "empty annotation",
+			annotation: "",
+			want:       nil,
+		},
+		{
+			name:       "configMapRef and secretRef entries",
+			annotation: "- configMapRef:\n    name: my-config\n- secretRef:\n    name: my-secret\n    optional: true\n",
+			want: []valuesFromRef{
+				{ConfigMapRef: &valuesFromSourceRef{Name: "my-config"}},
+				{SecretRef: &valuesFromSourceRef{Name: "my-secret", Optional: true}},
+			},
+		},
+		{
+			name:       "malformed yaml",
+			annotation: "not: [valid",
+			wantErr:    true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := parseValuesFrom(c.annotation)
+			if c.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseValuesFrom returned error: %s", err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("parseValuesFrom() = %#v, want %#v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseSetValues(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation string
+		want       chartutil.Values
+		wantErr    bool
+	}{
+		{
+			name:       "empty annotation",
+			annotation: "",
+			want:       chartutil.Values{},
+		},
+		{
+			name:       "simple key=value pairs",
+			annotation: "foo=bar,baz=1",
+			want:       chartutil.Values{"foo": "bar", "baz": int64(1)},
+		},
+		{
+			name:       "nested keys",
+			annotation: "image.tag=v1.2.3",
+			want: chartutil.Values{
+				"image": map[string]interface{}{"tag": "v1.2.3"},
+			},
+		},
+		{
+			name:       "malformed",
+			annotation: "foo",
+			wantErr:    true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := parseSetValues(c.annotation)
+			if c.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSetValues returned error: %s", err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("parseSetValues() = %#v, want %#v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeYAML(t *testing.T) {
+	in := map[interface{}]interface{}{
+		"foo": "bar",
+		"nested": map[interface{}]interface{}{
+			"baz": 1,
+		},
+		"list": []interface{}{
+			map[interface{}]interface{}{"a": "b"},
+		},
+	}
+	want := map[string]interface{}{
+		"foo": "bar",
+		"nested": map[string]interface{}{
+			"baz": 1,
+		},
+		"list": []interface{}{
+			map[string]interface{}{"a": "b"},
+		},
+	}
+	got := normalizeYAML(in)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("normalizeYAML() = %#v, want %#v", got, want)
+	}
+}
+
+func TestDataToValues(t *testing.T) {
+	data := map[string]string{
+		"values.yaml": "foo: bar\nnested:\n  baz: 1\n",
+		"extra.yaml":  "- one\n- two\n",
+	}
+	want := chartutil.Values{
+		"foo":        "bar",
+		"nested":     map[string]interface{}{"baz": float64(1)},
+		"extra.yaml": []interface{}{"one", "two"},
+	}
+
+	got, err := dataToValues(data)
+	if err != nil {
+		t.Fatalf("dataToValues returned error: %s", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dataToValues() = %#v, want %#v", got, want)
+	}
+}
+
+func TestDataToValuesMalformedKey(t *testing.T) {
+	_, err := dataToValues(map[string]string{"extra.yaml": "not: [valid"})
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+}
+
+func TestSplitSpecValuesFrom(t *testing.T) {
+	cases := []struct {
+		name       string
+		spec       interface{}
+		wantInline interface{}
+		wantRefs   []specValuesFromRef
+		wantErr    bool
+	}{
+		{
+			name:       "non-map spec is returned unchanged",
+			spec:       "not-a-map",
+			wantInline: "not-a-map",
+			wantRefs:   nil,
+		},
+		{
+			name:       "map with no valuesFrom key",
+			spec:       map[string]interface{}{"foo": "bar"},
+			wantInline: map[string]interface{}{"foo": "bar"},
+			wantRefs:   nil,
+		},
+		{
+			name: "map with valuesFrom list",
+			spec: map[string]interface{}{
+				"foo": "bar",
+				"valuesFrom": []interface{}{
+					map[string]interface{}{
+						"configMapKeyRef": map[string]interface{}{"name": "my-config", "key": "values"},
+					},
+				},
+			},
+			wantInline: map[string]interface{}{"foo": "bar"},
+			wantRefs: []specValuesFromRef{
+				{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "my-config"},
+					Key:                  "values",
+				}},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			inline, refs, err := splitSpecValuesFrom(c.spec)
+			if c.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("splitSpecValuesFrom returned error: %s", err)
+			}
+			if !reflect.DeepEqual(inline, c.wantInline) {
+				t.Errorf("inline = %#v, want %#v", inline, c.wantInline)
+			}
+			if !reflect.DeepEqual(refs, c.wantRefs) {
+				t.Errorf("refs = %#v, want %#v", refs, c.wantRefs)
+			}
+		})
+	}
+}
+
+// TestApplyValuesOverridesPrecedence exercises the precedence order
+// documented on applyValuesOverrides: CR spec < ConfigMap < Secret <
+// annotation-set. Each source sets a different key from the CR spec's, plus
+// one key ("level") that every source defines, so the winning value at the
+// end reveals which source actually took precedence.
+func TestApplyValuesOverridesPrecedence(t *testing.T) {
+	u := &unstructured.Unstructured{}
+	u.SetAnnotations(map[string]string{
+		valuesFromAnnotation: "- configMapRef:\n    name: my-config\n- secretRef:\n    name: my-secret\n",
+		setAnnotation:        "level=set,fromSet=true",
+	})
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metaObj("my-config", "my-ns"),
+		Data: map[string]string{
+			"values.yaml": "level: configmap\nfromConfigMap: true\n",
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metaObj("my-secret", "my-ns"),
+		Data: map[string][]byte{
+			"values.yaml": []byte("level: secret\nfromSecret: true\n"),
+		},
+	}
+
+	m := &manager{
+		resource:  u,
+		namespace: "my-ns",
+		client:    fake.NewFakeClientWithScheme(scheme.Scheme, cm, secret),
+		values:    chartutil.Values{"level": "spec", "fromSpec": true},
+		chart:     &chart.Chart{},
+	}
+
+	if err := m.applyValuesOverrides(context.TODO()); err != nil {
+		t.Fatalf("applyValuesOverrides returned error: %s", err)
+	}
+
+	if got := m.values["level"]; got != "set" {
+		t.Errorf("values[level] = %v, want %q: annotation-set must win over every other source", got, "set")
+	}
+	for _, key := range []string{"fromSpec", "fromConfigMap", "fromSecret", "fromSet"} {
+		if got, ok := m.values[key]; !ok || got != true {
+			t.Errorf("values[%s] = %v, want true: lower-precedence sources must still contribute keys the higher ones don't set", key, got)
+		}
+	}
+}